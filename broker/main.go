@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// main starts a broker which runs the world on the workers registered with it for a controller.
+func main() {
+	port := flag.String(
+		"port",
+		"8030",
+		"Specify the port to listen on for workers and controllers. Defaults to 8030.")
+
+	flag.Parse()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:"+*port)
+	util.Check(err)
+	fmt.Println("Broker listening on", listener.Addr())
+
+	util.Check(gol.ServeBroker(listener))
+}
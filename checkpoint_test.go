@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestCheckpoint writes checkpoints during a run, then resumes from the last one, checking the initial
+// CellFlipped events are the world at the checkpoint, that turns carry on from it and that the final world is unchanged.
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-checkpoint")
+	util.Check(err)
+	defer os.RemoveAll(dir)
+
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 4, CheckpointDir: dir, CheckpointTurns: 30}
+	runFinalCells(p)
+
+	files, err := ioutil.ReadDir(dir)
+	util.Check(err)
+	if len(files) != 1 || files[0].Name() != "64x64.checkpoint.pgm" {
+		t.Fatalf("Expected only 64x64.checkpoint.pgm in the checkpoint directory, got %v", files)
+	}
+
+	alive := readAliveCounts(p.ImageWidth, p.ImageHeight)
+	expectedAlive := readAliveCells("check/images/64x64x100.pgm", p.ImageWidth, p.ImageHeight)
+	resume := gol.Params{Resume: filepath.Join(dir, "64x64.checkpoint.pgm")}
+	events := make(chan gol.Event)
+	go gol.Run(resume, events, nil)
+
+	flipped := make(map[util.Cell]bool)
+	var cells []util.Cell
+	first := 0
+	for event := range events {
+		switch e := event.(type) {
+		case gol.CellFlipped:
+			if first == 0 && e.CompletedTurns != 90 {
+				t.Fatalf("Expected initial CellFlipped events at turn 90, got %v", e.CompletedTurns)
+			}
+			flipped[e.Cell] = !flipped[e.Cell]
+		case gol.TurnComplete:
+			if first == 0 {
+				first = e.CompletedTurns
+			}
+			if count := countFlipped(flipped); count != alive[e.CompletedTurns] {
+				t.Errorf("At turn %v expected %v alive cells from CellFlipped events, got %v instead", e.CompletedTurns, alive[e.CompletedTurns], count)
+			}
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+		}
+	}
+	if first != 91 {
+		t.Errorf("Expected to resume from turn 91, got %v", first)
+	}
+	assertEqualBoard(t, cells, expectedAlive, p)
+}
+
+// TestCheckpointParams checks a run resumed from a checkpoint keeps the rule, topology and engine it was started with.
+func TestCheckpointParams(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-checkpoint")
+	util.Check(err)
+	defer os.RemoveAll(dir)
+
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 3, Engine: gol.BitEngine}
+	util.Check(p.Rule.Set("B36/S23"))
+	util.Check(p.Topology.Set("plane"))
+	expectedAlive := runFinalCells(p)
+
+	p.CheckpointDir, p.CheckpointTurns = dir, 40
+	runFinalCells(p)
+	resume := gol.Params{Resume: filepath.Join(dir, "64x64.checkpoint.pgm")}
+	assertEqualBoard(t, runFinalCells(resume), expectedAlive, p)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestRunContextCancel stops reading events part way through a run and cancels it,
+// checking RunContext returns promptly, closes events and leaves no goroutines behind.
+func TestRunContextCancel(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+
+	for _, batch := range []bool{false, true} {
+		p := gol.Params{Turns: 100000000, Threads: 8, ImageWidth: 512, ImageHeight: 512, BatchFlips: batch}
+		ctx, cancel := context.WithCancel(context.Background())
+		events := make(chan gol.Event)
+		returned := make(chan error)
+		go func() {
+			returned <- gol.RunContext(ctx, p, events, nil)
+		}()
+
+		for event := range events {
+			if e, ok := event.(gol.TurnComplete); ok && e.CompletedTurns == 10 {
+				break
+			}
+		}
+		cancel()
+
+		select {
+		case err := <-returned:
+			if err != context.Canceled {
+				t.Errorf("Expected %v, got %v", context.Canceled, err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("RunContext did not return within 2s of being cancelled")
+		}
+		if _, ok := <-events; ok {
+			t.Error("Expected events to be closed")
+		}
+	}
+
+	//the workers exit once they see the pool has closed
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if count := runtime.NumGoroutine(); count > goroutines {
+		t.Errorf("Expected at most %v goroutines once cancelled, got %v", goroutines, count)
+	}
+}
+
+// runError runs p to completion, returning the error from RunContext once events has been closed.
+func runError(p gol.Params) error {
+	events := make(chan gol.Event)
+	returned := make(chan error, 1)
+	go func() {
+		returned <- gol.RunContext(context.Background(), p, events, nil)
+	}()
+	for range events {
+	}
+	return <-returned
+}
+
+// TestRunContextErrors checks the errors returned for a missing input image, bad headers, pixel data ending early,
+// mismatched dimensions and an output directory which cannot be written to.
+func TestRunContextErrors(t *testing.T) {
+	err := runError(gol.Params{ImageWidth: 17, ImageHeight: 17})
+	if _, ok := err.(*gol.MissingImageError); !ok {
+		t.Errorf("Expected a MissingImageError for a missing image, got %v", err)
+	}
+
+	images := map[string]string{
+		"images/3x3.pgm": "P6\n3 3\n255\n000000000",
+		"images/4x4.pgm": "P5\n4 4\n0\n0000000000000000",
+		"images/3x5.pgm": "P5\n5 3\n255\n000000000000000",
+		"images/6x6.pgm": "P5\n6 6\n255\n000000",
+	}
+	for filename, data := range images {
+		util.Check(ioutil.WriteFile(filename, []byte(data), 0644))
+		defer os.Remove(filename)
+	}
+	for _, size := range []int{3, 4} {
+		err = runError(gol.Params{ImageWidth: size, ImageHeight: size})
+		if _, ok := err.(*gol.HeaderError); !ok {
+			t.Errorf("Expected a HeaderError for images/%vx%v.pgm, got %v", size, size, err)
+		}
+	}
+	err = runError(gol.Params{ImageWidth: 6, ImageHeight: 6})
+	if _, ok := err.(*gol.PixelError); !ok {
+		t.Errorf("Expected a PixelError for images/6x6.pgm, got %v", err)
+	}
+	err = runError(gol.Params{ImageWidth: 3, ImageHeight: 5})
+	if e, ok := err.(*gol.DimensionError); !ok || e.Width != 5 || e.Height != 3 {
+		t.Errorf("Expected a DimensionError for a 5x3 image, got %v", err)
+	}
+
+	//run from a directory where out is a file rather than a directory
+	dir, err := ioutil.TempDir("", "gol-output")
+	util.Check(err)
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	util.Check(err)
+	util.Check(os.Symlink(filepath.Join(wd, "images"), filepath.Join(dir, "images")))
+	util.Check(ioutil.WriteFile(filepath.Join(dir, "out"), nil, 0644))
+	util.Check(os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	err = runError(gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 1, Threads: 2})
+	if _, ok := err.(*gol.OutputError); !ok {
+		t.Errorf("Expected an OutputError when out/ cannot be written, got %v", err)
+	}
+}
+
+// TestRunContextClosedCommands checks that closing the commands channel leaves the run carrying on to the final turn.
+func TestRunContextClosedCommands(t *testing.T) {
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 10, Threads: 2}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	commands := make(chan rune)
+	close(commands)
+	events := make(chan gol.Event)
+	returned := make(chan error, 1)
+	go func() {
+		returned <- gol.RunContext(ctx, p, events, commands)
+	}()
+
+	completed := 0
+	for event := range events {
+		if e, ok := event.(gol.FinalTurnComplete); ok {
+			completed = e.CompletedTurns
+		}
+	}
+	if err := <-returned; err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if completed != p.Turns {
+		t.Errorf("Expected %v turns to complete, got %v", p.Turns, completed)
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestHelperProcess is not a real test: it runs a broker or worker when the tests start themselves as another process.
+func TestHelperProcess(t *testing.T) {
+	role := os.Getenv("GOL_HELPER")
+	if role == "" {
+		return
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	util.Check(err)
+	fmt.Println(listener.Addr())
+
+	switch role {
+	case "broker":
+		err = gol.ServeBroker(listener)
+	case "worker":
+		err = gol.ServeWorker(listener, os.Getenv("GOL_BROKER"))
+	}
+	util.Check(err)
+	os.Exit(0)
+}
+
+// startHelper starts the test binary as a broker or worker, returning the process and the address it listens on.
+func startHelper(t *testing.T, role, broker string) (*exec.Cmd, string) {
+	args := []string{"-test.run=^TestHelperProcess$"}
+	if flag.Lookup("noVis") != nil {
+		args = append(args, "-noVis")
+	}
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "GOL_HELPER="+role, "GOL_BROKER="+broker)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	util.Check(err)
+	util.Check(cmd.Start())
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if _, _, err := net.SplitHostPort(scanner.Text()); err == nil {
+			go func() {
+				for scanner.Scan() {
+				}
+			}()
+			return cmd, scanner.Text()
+		}
+	}
+	t.Fatalf("%v did not report its address", role)
+	return nil, ""
+}
+
+// waitExit fails the test unless cmd exits within the timeout.
+func waitExit(t *testing.T, cmd *exec.Cmd, timeout time.Duration) {
+	exited := make(chan error)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	select {
+	case err := <-exited:
+		if err != nil {
+			t.Errorf("process %v exited with %v", cmd.Process.Pid, err)
+		}
+	case <-time.After(timeout):
+		t.Errorf("process %v did not exit within %v", cmd.Process.Pid, timeout)
+		_ = cmd.Process.Kill()
+	}
+}
+
+// TestDistributed runs a broker and 3 workers as separate processes, checking the final alive cells
+// and the alive cells implied by the CellFlipped events of every turn, then that q stops a run early
+// and k shuts down the broker and workers.
+func TestDistributed(t *testing.T) {
+	brokerCmd, broker := startHelper(t, "broker", "")
+	defer brokerCmd.Process.Kill()
+	var workerCmds []*exec.Cmd
+	for i := 0; i < 3; i++ {
+		workerCmd, _ := startHelper(t, "worker", broker)
+		defer workerCmd.Process.Kill()
+		workerCmds = append(workerCmds, workerCmd)
+	}
+
+	tests := []gol.Params{
+		{ImageWidth: 16, ImageHeight: 16},
+		{ImageWidth: 64, ImageHeight: 64},
+		{ImageWidth: 512, ImageHeight: 512},
+	}
+	for _, p := range tests {
+		p.Server = broker
+		alive := readAliveCounts(p.ImageWidth, p.ImageHeight)
+		for _, turns := range []int{0, 1, 100} {
+			p.Turns = turns
+			expectedAlive := readAliveCells(
+				"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, turns),
+				p.ImageWidth,
+				p.ImageHeight,
+			)
+			t.Run(fmt.Sprintf("%dx%dx%d", p.ImageWidth, p.ImageHeight, p.Turns), func(t *testing.T) {
+				events := make(chan gol.Event)
+				go gol.Run(p, events, nil)
+				flipped := make(map[util.Cell]bool)
+				var cells []util.Cell
+				for event := range events {
+					switch e := event.(type) {
+					case gol.CellFlipped:
+						flipped[e.Cell] = !flipped[e.Cell]
+					case gol.TurnComplete:
+						if count := countFlipped(flipped); count != alive[e.CompletedTurns] {
+							t.Errorf("At turn %v expected %v alive cells from CellFlipped events, got %v instead", e.CompletedTurns, alive[e.CompletedTurns], count)
+						}
+					case gol.FinalTurnComplete:
+						cells = e.Alive
+					}
+				}
+				assertEqualBoard(t, cells, expectedAlive, p)
+			})
+		}
+	}
+
+	for _, key := range []rune{'q', 'k'} {
+		t.Run(fmt.Sprintf("keys-%c", key), func(t *testing.T) {
+			p := gol.Params{Turns: 100000000, ImageWidth: 512, ImageHeight: 512, Server: broker}
+			alive := readAliveCounts(p.ImageWidth, p.ImageHeight)
+			events := make(chan gol.Event)
+			keyPresses := make(chan rune, 2)
+			go gol.Run(p, events, keyPresses)
+
+			counts, turn := 0, 0
+			for event := range events {
+				switch e := event.(type) {
+				case gol.TurnComplete:
+					turn++
+				case gol.AliveCellsCount:
+					if e.CompletedTurns != turn {
+						t.Fatalf("Expected turn to be %v, got %v instead", turn, e.CompletedTurns)
+					}
+					if e.CompletedTurns > 0 && e.CompletedTurns <= 10000 && e.CellsCount != alive[e.CompletedTurns] {
+						t.Fatalf("At turn %v expected %v alive cells, got %v instead", e.CompletedTurns, alive[e.CompletedTurns], e.CellsCount)
+					}
+					counts++
+					if counts == 3 {
+						keyPresses <- key
+					}
+				}
+			}
+			if counts < 3 {
+				t.Fatal("not enough AliveCellsCount events received")
+			}
+		})
+	}
+
+	waitExit(t, brokerCmd, 5*time.Second)
+	for _, workerCmd := range workerCmds {
+		waitExit(t, workerCmd, 5*time.Second)
+	}
+}
+
+// TestDistributedRules checks that the broker gives the same results as the byte engine for other rules and topologies,
+// which read rows beyond those around each strip.
+func TestDistributedRules(t *testing.T) {
+	brokerCmd, broker := startHelper(t, "broker", "")
+	defer brokerCmd.Process.Kill()
+	for i := 0; i < 3; i++ {
+		workerCmd, _ := startHelper(t, "worker", broker)
+		defer workerCmd.Process.Kill()
+	}
+
+	for _, notation := range []string{"B36/S23", "B2/S/C3", "R5,C0,M1,S34..58,B34..45,NM"} {
+		for _, topology := range []string{"torus", "plane", "reflective", "klein", "cross", "twisted+5"} {
+			p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 20, Threads: 3}
+			util.Check(p.Rule.Set(notation))
+			util.Check(p.Topology.Set(topology))
+			expectedAlive := runFinalCells(p)
+			p.Server = broker
+			t.Run(fmt.Sprintf("%v-%v", p.Rule, p.Topology), func(t *testing.T) {
+				assertEqualBoard(t, runFinalCells(p), expectedAlive, p)
+			})
+		}
+	}
+}
+
+// TestWorkerFailure kills workers part way through a run, checking that the broker reports each failure
+// and still matches the byte engine, first with workers remaining and then with every worker replaced.
+func TestWorkerFailure(t *testing.T) {
+	brokerCmd, broker := startHelper(t, "broker", "")
+	defer brokerCmd.Process.Kill()
+	var workerCmds []*exec.Cmd
+	for i := 0; i < 4; i++ {
+		workerCmd, _ := startHelper(t, "worker", broker)
+		defer workerCmd.Process.Kill()
+		workerCmds = append(workerCmds, workerCmd)
+	}
+	kill := func(cmd *exec.Cmd) {
+		util.Check(cmd.Process.Kill())
+		_ = cmd.Wait()
+	}
+
+	//the controller collects up to maxBufferedTurns updates at once and the broker then runs up to maxBufferedTurns
+	//further ahead, so it is at most 128 turns ahead and both kills happen long before it finishes
+	p := gol.Params{Turns: 400, ImageWidth: 512, ImageHeight: 512, Threads: 4}
+	alive := readAliveCounts(p.ImageWidth, p.ImageHeight)
+	expectedAlive := runFinalCells(p)
+	p.Server = broker
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+
+	flipped := make(map[util.Cell]bool)
+	var cells []util.Cell
+	failures := 0
+	for event := range events {
+		switch e := event.(type) {
+		case gol.CellFlipped:
+			flipped[e.Cell] = !flipped[e.Cell]
+		case gol.WorkerFailed:
+			t.Log(e)
+			failures++
+		case gol.TurnComplete:
+			if count := countFlipped(flipped); count != alive[e.CompletedTurns] {
+				t.Errorf("At turn %v expected %v alive cells from CellFlipped events, got %v instead", e.CompletedTurns, alive[e.CompletedTurns], count)
+			}
+			switch e.CompletedTurns {
+			case 1:
+				kill(workerCmds[0])
+				kill(workerCmds[1])
+			case 20:
+				kill(workerCmds[2])
+				kill(workerCmds[3])
+				workerCmd, _ := startHelper(t, "worker", broker)
+				defer workerCmd.Process.Kill()
+			}
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+		}
+	}
+	assertEqualBoard(t, cells, expectedAlive, p)
+	if failures != 4 {
+		t.Errorf("Expected 4 WorkerFailed events, got %v", failures)
+	}
+}
+
+// TestDetach detaches a controller from a run on the broker, checking the run carries on without it,
+// then attaches a new controller without giving the size of the world and checks it is resynchronised with the whole world before following the remaining turns.
+func TestDetach(t *testing.T) {
+	brokerCmd, broker := startHelper(t, "broker", "")
+	defer brokerCmd.Process.Kill()
+	for i := 0; i < 2; i++ {
+		workerCmd, _ := startHelper(t, "worker", broker)
+		defer workerCmd.Process.Kill()
+	}
+
+	p := gol.Params{Turns: 300, ImageWidth: 512, ImageHeight: 512, Server: broker}
+	alive := readAliveCounts(p.ImageWidth, p.ImageHeight)
+	events := make(chan gol.Event)
+	keyPresses := make(chan rune, 2)
+	go gol.Run(p, events, keyPresses)
+
+	detached := 0
+	for event := range events {
+		switch e := event.(type) {
+		case gol.TurnComplete:
+			detached = e.CompletedTurns
+			if detached == 10 {
+				keyPresses <- 'd'
+			}
+		case gol.FinalTurnComplete, gol.ImageOutputComplete:
+			t.Errorf("Expected no %T event after detaching", e)
+		}
+	}
+	if detached >= p.Turns {
+		t.Fatalf("Expected to detach before turn %v, got to turn %v", p.Turns, detached)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	//the size, turns, rule and topology come from the broker
+	attach, err := gol.InputParams(gol.Params{Server: broker, Attach: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attach.ImageWidth != 512 || attach.ImageHeight != 512 {
+		t.Fatalf("Expected the size of the simulation on the broker to be 512x512, got %vx%v", attach.ImageWidth, attach.ImageHeight)
+	}
+	events = make(chan gol.Event)
+	go gol.Run(attach, events, nil)
+
+	flipped := make(map[util.Cell]bool)
+	var cells []util.Cell
+	first := 0
+	for event := range events {
+		switch e := event.(type) {
+		case gol.CellFlipped:
+			flipped[e.Cell] = !flipped[e.Cell]
+		case gol.TurnComplete:
+			if first == 0 {
+				first = e.CompletedTurns
+			}
+			if count := countFlipped(flipped); count != alive[e.CompletedTurns] {
+				t.Errorf("At turn %v expected %v alive cells from CellFlipped events, got %v instead", e.CompletedTurns, alive[e.CompletedTurns], count)
+			}
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+			if e.CompletedTurns != p.Turns {
+				t.Errorf("Expected the final turn to be %v, got %v instead", p.Turns, e.CompletedTurns)
+			}
+		}
+	}
+	if first <= detached+1 {
+		t.Errorf("Expected the run to carry on after detaching at turn %v, attached at turn %v", detached, first-1)
+	}
+	if len(cells) != alive[p.Turns] {
+		t.Errorf("Expected %v alive cells after %v turns, got %v instead", alive[p.Turns], p.Turns, len(cells))
+	}
+}
+
+// TestBrokerFailure kills the broker part way through a run, checking RunContext returns an error and closes events.
+func TestBrokerFailure(t *testing.T) {
+	brokerCmd, broker := startHelper(t, "broker", "")
+	defer brokerCmd.Process.Kill()
+	workerCmd, _ := startHelper(t, "worker", broker)
+	defer workerCmd.Process.Kill()
+
+	p := gol.Params{Turns: 100000000, ImageWidth: 64, ImageHeight: 64, Server: broker}
+	events := make(chan gol.Event)
+	returned := make(chan error, 1)
+	go func() {
+		returned <- gol.RunContext(context.Background(), p, events, nil)
+	}()
+
+	for event := range events {
+		if e, ok := event.(gol.TurnComplete); ok && e.CompletedTurns == 5 {
+			util.Check(brokerCmd.Process.Kill())
+			_ = brokerCmd.Wait()
+		}
+	}
+	select {
+	case err := <-returned:
+		if err == nil {
+			t.Error("Expected an error once the broker was killed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunContext did not return within 5s of closing events")
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// engines lists every engine and the options it supports.
+var engines = []gol.Params{
+	{Engine: gol.ByteEngine},
+	{Engine: gol.ByteEngine, ActiveTiles: true},
+	{Engine: gol.ByteEngine, Tiles: true},
+	{Engine: gol.ByteEngine, Tiles: true, TileSize: 5},
+	{Engine: gol.ByteEngine, ActiveTiles: true, Tiles: true, TileSize: 12},
+	{Engine: gol.BitEngine},
+	{Engine: gol.HashLifeEngine},
+	{Engine: gol.HaloEngine},
+}
+
+func engineName(p gol.Params) string {
+	name := p.Engine.String()
+	if p.ActiveTiles {
+		name += "-active"
+	}
+	if p.Tiles {
+		name += fmt.Sprintf("-tiles%d", p.TileSize)
+	}
+	return name
+}
+
+// TestEngines tests every engine on 16x16, 64x64 and 512x512 images on 0, 1 and 100 turns,
+// checking the final alive cells and the alive cells implied by the CellFlipped events of every turn.
+// Counting the flips of every turn on 512x512 is slow, so only the plain byte engine runs it with every number of threads.
+func TestEngines(t *testing.T) {
+	tests := []gol.Params{
+		{ImageWidth: 16, ImageHeight: 16},
+		{ImageWidth: 64, ImageHeight: 64},
+		{ImageWidth: 512, ImageHeight: 512},
+	}
+	for i, engine := range engines {
+		for _, p := range tests {
+			p.Engine = engine.Engine
+			p.ActiveTiles = engine.ActiveTiles
+			p.Tiles, p.TileSize = engine.Tiles, engine.TileSize
+			alive := readAliveCounts(p.ImageWidth, p.ImageHeight)
+			for _, turns := range []int{0, 1, 100} {
+				p.Turns = turns
+				expectedAlive := readAliveCells(
+					"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, turns),
+					p.ImageWidth,
+					p.ImageHeight,
+				)
+				counts := []int{1, 3, 8}
+				if p.ImageWidth == 512 && i > 0 {
+					counts = []int{3}
+				}
+				for _, threads := range counts {
+					p.Threads = threads
+					testName := fmt.Sprintf("%v-%dx%dx%d-%d", engineName(p), p.ImageWidth, p.ImageHeight, p.Turns, p.Threads)
+					t.Run(testName, func(t *testing.T) {
+						events := make(chan gol.Event)
+						go gol.Run(p, events, nil)
+						flipped := make(map[util.Cell]bool)
+						var cells []util.Cell
+						for event := range events {
+							switch e := event.(type) {
+							case gol.CellFlipped:
+								flipped[e.Cell] = !flipped[e.Cell]
+							case gol.TurnComplete:
+								if count := countFlipped(flipped); count != alive[e.CompletedTurns] {
+									t.Errorf("At turn %v expected %v alive cells from CellFlipped events, got %v instead", e.CompletedTurns, alive[e.CompletedTurns], count)
+								}
+							case gol.FinalTurnComplete:
+								cells = e.Alive
+							}
+						}
+						assertEqualBoard(t, cells, expectedAlive, p)
+					})
+				}
+			}
+		}
+	}
+}
+
+func countFlipped(flipped map[util.Cell]bool) int {
+	count := 0
+	for _, alive := range flipped {
+		if alive {
+			count++
+		}
+	}
+	return count
+}
+
+// TestBitEngine checks that the bit engine gives the same results as the byte engine
+// for other rules and on a plane, and on a soup whose rows end part way through a word.
+func TestBitEngine(t *testing.T) {
+	for _, topology := range []string{"torus", "plane"} {
+		p := gol.Params{ImageWidth: 130, ImageHeight: 70, Turns: 50, Threads: 4, Soup: true, Seed: 7}
+		util.Check(p.Topology.Set(topology))
+		expectedAlive := runFinalCells(p)
+		p.Engine = gol.BitEngine
+		t.Run(fmt.Sprintf("soup-130x70-%v", p.Topology), func(t *testing.T) {
+			assertEqualBoard(t, runFinalCells(p), expectedAlive, p)
+		})
+	}
+
+	for _, notation := range []string{"B36/S23", "B3678/S34678", "B2/S", "B0123478/S34678"} {
+		for _, topology := range []string{"torus", "plane"} {
+			p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 50, Threads: 4}
+			util.Check(p.Rule.Set(notation))
+			util.Check(p.Topology.Set(topology))
+			expectedAlive := runFinalCells(p)
+			p.Engine = gol.BitEngine
+			t.Run(fmt.Sprintf("%v-%v", p.Rule, p.Topology), func(t *testing.T) {
+				assertEqualBoard(t, runFinalCells(p), expectedAlive, p)
+			})
+		}
+	}
+}
+
+// TestHaloEngine checks that exchanging ghost rows between strips gives the same results as the byte engine
+// for other rules and on a plane, including with more threads than rows,
+// and that rules counting anything but the 8 cells of the Moore neighbourhood are rejected.
+func TestHaloEngine(t *testing.T) {
+	for _, notation := range []string{"R1,C0,M0,S1..2,B1..1,NN", "R1,C0,M1,S3..4,B3..3,NM", "R2,C0,M0,S3..5,B3..4,NM"} {
+		p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 1, Threads: 4, Engine: gol.HaloEngine}
+		util.Check(p.Rule.Set(notation))
+		if err := runError(p); err == nil {
+			t.Errorf("Expected the halo engine to reject %v", p.Rule)
+		}
+	}
+
+	for _, notation := range []string{"B36/S23", "B2/S/C3", "B0123478/S34678"} {
+		for _, topology := range []string{"torus", "plane"} {
+			for _, threads := range []int{1, 4, 20} {
+				p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 50, Threads: threads}
+				util.Check(p.Rule.Set(notation))
+				util.Check(p.Topology.Set(topology))
+				expectedAlive := runFinalCells(p)
+				p.Engine = gol.HaloEngine
+				t.Run(fmt.Sprintf("%v-%v-%d", p.Rule, p.Topology, threads), func(t *testing.T) {
+					assertEqualBoard(t, runFinalCells(p), expectedAlive, p)
+				})
+			}
+		}
+	}
+}
+
+// TestActiveTiles checks that only recomputing active tiles gives the same results
+// for other rules and topologies, with the world split into strips or into tiles not aligned with the active tiles.
+func TestActiveTiles(t *testing.T) {
+	for _, notation := range []string{"B36/S23", "B2/S/C3", "B0123478/S34678", "R5,C0,M1,S34..58,B34..45,NM"} {
+		for _, topology := range []string{"torus", "plane", "reflective", "klein", "cross", "twisted+5"} {
+			for _, size := range []int{16, 64} {
+				p := gol.Params{ImageWidth: size, ImageHeight: size, Turns: 50, Threads: 3}
+				util.Check(p.Rule.Set(notation))
+				util.Check(p.Topology.Set(topology))
+				expectedAlive := runFinalCells(p)
+				p.ActiveTiles = true
+				t.Run(fmt.Sprintf("%v-%v-%d", p.Rule, p.Topology, size), func(t *testing.T) {
+					assertEqualBoard(t, runFinalCells(p), expectedAlive, p)
+				})
+				p.Tiles, p.TileSize = true, 12
+				t.Run(fmt.Sprintf("%v-%v-%d-tiles", p.Rule, p.Topology, size), func(t *testing.T) {
+					assertEqualBoard(t, runFinalCells(p), expectedAlive, p)
+				})
+			}
+		}
+	}
+}
+
+// TestHashLife checks that the hashlife engine reaches the period 2 ash of the 512x512 image
+// after far more turns than could be processed one at a time.
+func TestHashLife(t *testing.T) {
+	for turns, expected := range map[int]int{10000000000: 5565, 10000000001: 5567} {
+		p := gol.Params{ImageWidth: 512, ImageHeight: 512, Turns: turns, Engine: gol.HashLifeEngine}
+		t.Run(fmt.Sprint(turns), func(t *testing.T) {
+			if cells := runFinalCells(p); len(cells) != expected {
+				t.Errorf("expected %v alive cells, got %v", expected, len(cells))
+			}
+		})
+	}
+}
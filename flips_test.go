@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestBatchFlips tests every engine with the cells changed in each turn batched into CellsFlipped events,
+// checking there is exactly one event before every turn, none of the per-cell events, and the alive cells it implies.
+func TestBatchFlips(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, BatchFlips: true}
+	alive := readAliveCounts(p.ImageWidth, p.ImageHeight)
+	expectedAlive := readAliveCells("check/images/64x64x100.pgm", p.ImageWidth, p.ImageHeight)
+	for _, engine := range engines {
+		p.Engine = engine.Engine
+		p.ActiveTiles = engine.ActiveTiles
+		p.Tiles, p.TileSize = engine.Tiles, engine.TileSize
+		for _, threads := range []int{1, 3, 8} {
+			p.Threads = threads
+			t.Run(fmt.Sprintf("%v-%d", engineName(p), p.Threads), func(t *testing.T) {
+				events := make(chan gol.Event)
+				go gol.Run(p, events, nil)
+				flipped := make(map[util.Cell]bool)
+				var cells []util.Cell
+				batches := 0
+				for event := range events {
+					switch e := event.(type) {
+					case gol.CellFlipped, gol.CellStateChanged:
+						t.Fatalf("unexpected per-cell event %T while batching", e)
+					case gol.CellsFlipped:
+						if e.Values != nil {
+							t.Fatalf("unexpected values for a rule which is not a Generations rule")
+						}
+						for _, cell := range e.Cells {
+							flipped[cell] = !flipped[cell]
+						}
+						batches++
+					case gol.TurnComplete:
+						//the initial cells come in a batch of their own
+						expected := 1
+						if e.CompletedTurns == 1 {
+							expected = 2
+						}
+						if batches != expected {
+							t.Fatalf("At turn %v expected %v CellsFlipped events, got %v", e.CompletedTurns, expected, batches)
+						}
+						batches = 0
+						if count := countFlipped(flipped); count != alive[e.CompletedTurns] {
+							t.Errorf("At turn %v expected %v alive cells from CellsFlipped events, got %v instead", e.CompletedTurns, alive[e.CompletedTurns], count)
+						}
+					case gol.FinalTurnComplete:
+						cells = e.Alive
+					}
+				}
+				assertEqualBoard(t, cells, expectedAlive, p)
+			})
+		}
+	}
+}
+
+// TestBatchGenerations checks CellsFlipped events carry the new value of each cell under a Generations rule.
+func TestBatchGenerations(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 10, Threads: 4, BatchFlips: true}
+	util.Check(p.Rule.Set("B2/S/C3"))
+
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	board := make(map[util.Cell]byte)
+	for event := range events {
+		switch e := event.(type) {
+		case gol.CellsFlipped:
+			if len(e.Values) != len(e.Cells) {
+				t.Fatalf("expected a value for each of the %v cells, got %v", len(e.Cells), len(e.Values))
+			}
+			for i, cell := range e.Cells {
+				board[cell] = e.Values[i]
+			}
+		case gol.FinalTurnComplete:
+			alive := 0
+			for _, value := range board {
+				if value == 255 {
+					alive++
+				}
+			}
+			if alive != len(e.Alive) {
+				t.Errorf("expected %v alive cells from events, got %v", len(e.Alive), alive)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestPatternFormats writes a 64x64 image after 10 turns in each pattern format, then runs each pattern
+// for 100 more turns, checking it gives the same result as running the image for 110 turns.
+func TestPatternFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-formats")
+	util.Check(err)
+	defer os.RemoveAll(dir)
+
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 110, Threads: 4, OutputDir: dir}
+	expectedAlive := runFinalCells(p)
+
+	formats := map[gol.Format]string{gol.Cells: "cells", gol.Life105: "105", gol.Life106: "106"}
+	for format, name := range formats {
+		t.Run(format.String(), func(t *testing.T) {
+			written := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 10, Threads: 4, OutputDir: dir, OutputFormat: format, OutputName: name}
+			runFinalCells(written)
+
+			//the Life formats do not record the size of the board, so it is given here
+			filename := filepath.Join(dir, name+".lif")
+			if format == gol.Cells {
+				filename = filepath.Join(dir, name+".cells")
+			}
+			pattern := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 4, InputPath: filename, OutputDir: dir}
+			assertEqualBoard(t, runFinalCells(pattern), expectedAlive, p)
+		})
+	}
+}
+
+// TestDetectFormat loads patterns whose names do not match their format, checking the format is detected by content.
+func TestDetectFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-formats")
+	util.Check(err)
+	defer os.RemoveAll(dir)
+
+	patterns := map[string]string{
+		"cells":   "!Name: Glider\n.O.\n..O\nOOO\n",
+		"life105": "#Life 1.05\n#D Glider\n#N\n#P -1 -1\n.*.\n..*\n***\n",
+		"life106": "#Life 1.06\n0 -1\n1 0\n-1 1\n0 1\n1 1\n",
+		"rle":     glider,
+	}
+	for name, content := range patterns {
+		t.Run(name, func(t *testing.T) {
+			assertGliderMoved(t, dir, name+".pgm", content)
+		})
+	}
+
+	filename := filepath.Join(dir, "unknown.rle")
+	util.Check(ioutil.WriteFile(filename, []byte("not a pattern\n"), 0644))
+	if _, ok := runError(gol.Params{Turns: 1, Threads: 1, InputPath: filename, OutputDir: dir}).(*gol.HeaderError); !ok {
+		t.Error("Expected a HeaderError for a file in no recognised format")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestPngOutput writes the 64x64 image as a PNG after 100 turns, checking it has the same alive cells as the pgm image.
+func TestPngOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-png")
+	util.Check(err)
+	defer os.RemoveAll(dir)
+
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 4, OutputDir: dir, OutputFormat: gol.PNG}
+	runFinalCells(p)
+
+	file, err := os.Open(filepath.Join(dir, "64x64x100.png"))
+	util.Check(err)
+	defer file.Close()
+	img, err := png.Decode(file)
+	util.Check(err)
+
+	var cells []util.Cell
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r != 0 {
+				cells = append(cells, util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	assertEqualBoard(t, cells, readAliveCells("check/images/64x64x100.pgm", 64, 64), p)
+}
+
+// TestGifRecorder records a 16x16 run of 10 turns every 3 turns at a scale of 2, checking a frame is recorded
+// after turns 3, 6, 9 and the final turn, and that the last frame shows the final alive cells.
+func TestGifRecorder(t *testing.T) {
+	for _, batch := range []bool{false, true} {
+		p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 10, Threads: 2, BatchFlips: batch, OutputDir: os.TempDir()}
+		recorder := gol.NewGifRecorder(p)
+		recorder.Every, recorder.Scale, recorder.Delay = 3, 2, 5
+
+		events := make(chan gol.Event)
+		go gol.Run(p, events, nil)
+		var alive []util.Cell
+		for event := range recorder.Record(events) {
+			if e, ok := event.(gol.FinalTurnComplete); ok {
+				alive = e.Alive
+			}
+		}
+
+		var buffer bytes.Buffer
+		util.Check(recorder.Save(&buffer))
+		animation, err := gif.DecodeAll(&buffer)
+		util.Check(err)
+		if len(animation.Image) != 4 || animation.Delay[0] != 5 {
+			t.Fatalf("Expected 4 frames shown for 5/100s, got %v shown for %v", len(animation.Image), animation.Delay)
+		}
+
+		last := animation.Image[len(animation.Image)-1]
+		if last.Bounds().Dx() != 32 || last.Bounds().Dy() != 32 {
+			t.Fatalf("Expected 32x32 frames, got %v", last.Bounds())
+		}
+		var cells []util.Cell
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 16; x++ {
+				if r, _, _, _ := last.At(2*x+1, 2*y+1).RGBA(); r != 0 {
+					cells = append(cells, util.Cell{X: x, Y: y})
+				}
+			}
+		}
+		assertEqualBoard(t, cells, alive, p)
+	}
+}
+
+// TestGifRecorderErrors checks that saving a GIF with no frames, or with frames too large to record, returns an error.
+func TestGifRecorderErrors(t *testing.T) {
+	p := gol.Params{ImageWidth: 512, ImageHeight: 512}
+	var buffer bytes.Buffer
+	if err := gol.NewGifRecorder(p).Save(&buffer); err == nil {
+		t.Errorf("Expected an error saving a GIF with no frames")
+	}
+
+	recorder := gol.NewGifRecorder(p)
+	recorder.Scale = 128
+	events := make(chan gol.Event, 1)
+	events <- gol.FinalTurnComplete{}
+	close(events)
+	for range recorder.Record(events) {
+	}
+	if err := recorder.Save(&buffer); err == nil {
+		t.Errorf("Expected an error saving 512x512 cells at a scale of 128")
+	}
+}
@@ -0,0 +1,120 @@
+package gol
+
+// activityTileSize is the width and height of the tiles tracked by activity,
+// unless the rule has a larger radius. Small tiles let stable cells near an oscillator be copied.
+const activityTileSize = 8
+
+// activity tracks which tiles of the world changed in the last turn.
+// A tile whose neighbouring tiles did not change will not change either, so it is copied instead of recomputed.
+type activity struct {
+	dimensions Dimensions
+	// tileSize is at least the radius of the rule, so a cell only reads from its own and adjacent tiles
+	tileSize int
+	columns  int
+	rows     int
+	// wraps is true if tiles on opposite edges are neighbours, as on a torus tiled exactly
+	wraps bool
+	// edge is the distance within which cells read beyond the edges of the world, making their tiles always active
+	edge int
+	// changed[y][column] is true if a cell of row y in the given column of tiles changed in the last turn
+	changed [][]bool
+	// active[row*columns+column] is true if the tile must be recomputed this turn
+	active []bool
+}
+
+func newActivity(dimensions Dimensions, rule Rule, topology Topology) *activity {
+	tileSize := activityTileSize
+	if rule.radius > tileSize {
+		tileSize = rule.radius
+	}
+	a := &activity{
+		dimensions: dimensions,
+		tileSize:   tileSize,
+		columns:    (dimensions.width + tileSize - 1) / tileSize,
+		rows:       (dimensions.height + tileSize - 1) / tileSize,
+	}
+
+	switch {
+	case topology.Surface == Torus && dimensions.width%tileSize == 0 && dimensions.height%tileSize == 0:
+		a.wraps = true
+	case topology.Surface != Plane:
+		a.edge = rule.radius
+	}
+
+	//every tile is recomputed on the first turn
+	a.changed = make([][]bool, dimensions.height)
+	for y := range a.changed {
+		a.changed[y] = make([]bool, a.columns)
+		for column := range a.changed[y] {
+			a.changed[y][column] = true
+		}
+	}
+	a.active = make([]bool, a.rows*a.columns)
+
+	return a
+}
+
+// prepare works out which tiles are active this turn from the tiles that changed in the last turn.
+func (a *activity) prepare() {
+	tileChanged := make([]bool, a.rows*a.columns)
+	for y, columns := range a.changed {
+		for column, changed := range columns {
+			if changed {
+				tileChanged[(y/a.tileSize)*a.columns+column] = true
+			}
+		}
+	}
+
+	for row := 0; row < a.rows; row++ {
+		for column := 0; column < a.columns; column++ {
+			active := a.nearEdge(row, column)
+			for dy := -1; dy <= 1 && !active; dy++ {
+				for dx := -1; dx <= 1 && !active; dx++ {
+					neighbourRow, neighbourColumn := row+dy, column+dx
+					if a.wraps {
+						neighbourRow, neighbourColumn = wrap(neighbourRow, a.rows), wrap(neighbourColumn, a.columns)
+					} else if neighbourRow < 0 || neighbourRow >= a.rows || neighbourColumn < 0 || neighbourColumn >= a.columns {
+						continue
+					}
+					active = tileChanged[neighbourRow*a.columns+neighbourColumn]
+				}
+			}
+			a.active[row*a.columns+column] = active
+		}
+	}
+}
+
+// nearEdge returns true if any cell of the tile reads cells beyond the edges of the world.
+func (a *activity) nearEdge(row, column int) bool {
+	if a.edge == 0 {
+		return false
+	}
+	tileEnd := func(index, length int) int {
+		end := (index + 1) * a.tileSize
+		if end > length {
+			end = length
+		}
+		return end
+	}
+
+	return column*a.tileSize < a.edge || tileEnd(column, a.dimensions.width) > a.dimensions.width-a.edge ||
+		row*a.tileSize < a.edge || tileEnd(row, a.dimensions.height) > a.dimensions.height-a.edge
+}
+
+// column returns the first column of tiles starting at or after x.
+func (a *activity) column(x int) int {
+	return (x + a.tileSize - 1) / a.tileSize
+}
+
+// columnRange returns the cells of row covered by a column of tiles.
+func (a *activity) columnRange(column int) Range {
+	end := (column + 1) * a.tileSize
+	if end > a.dimensions.width {
+		end = a.dimensions.width
+	}
+	return Range{start: column * a.tileSize, end: end}
+}
+
+func (a *activity) isActive(column, y int) bool {
+	return a.active[(y/a.tileSize)*a.columns+column]
+}
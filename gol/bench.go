@@ -4,43 +4,65 @@ import (
 	"fmt"
 	"os"
 	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
 )
 
-func BenchEverything(filename string) {
+// BenchEverything times the engine and options in p on each combination of turns, image size and threads,
+// appending the results to filename as CSV. The turns, image size and threads in p are ignored.
+// The byte engine is timed splitting the world into strips of rows and into tiles.
+// After the turns, size, threads and seconds of lab.csv, each row records the engine, the partition
+// and whether only active tiles were recomputed, so runs with different options can share a file.
+func BenchEverything(filename string, p Params) {
 	turns := []int{0, 1, 10, 100, 1000, 10000}
 	sizes := []int{256, 512}
 	threads := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	partitions := []bool{false}
+	if p.Engine == ByteEngine {
+		partitions = append(partitions, true)
+	}
 	for _, turn := range turns {
 		for _, size := range sizes {
 			for _, thread := range threads {
-				fmt.Printf("Bench With Turns=%d Size=%d Threads=%d\n", turn, size, thread)
-
-				//initialise the slices
-				dimensions := Dimensions{width: size, height: size}
-				active_world := readPgmImage(dimensions)
-				other_world := newWorld(dimensions)
-
-				//run an individual bench
-				start := time.Now()
-				bareProcessTurns(active_world, other_world, turn, thread)
-				elapsed := time.Since(start)
-
-				//append to result
-				f, _ := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
-				defer f.Close()
-				f.WriteString(fmt.Sprintf("%d, %d, %d, %f\n", turn, size, thread, elapsed.Seconds()))
+				for _, tiles := range partitions {
+					fmt.Printf("Bench With Engine=%v ActiveTiles=%v Tiles=%v Turns=%d Size=%d Threads=%d\n", p.Engine, p.ActiveTiles, tiles, turn, size, thread)
+
+					//initialise the backend
+					p.Turns, p.ImageWidth, p.ImageHeight, p.Threads, p.Tiles = turn, size, size, thread, tiles
+					dimensions := Dimensions{width: size, height: size}
+					world, _, err := readImage(image_filename(dimensions), dimensions, 0, 0, Threshold)
+					util.Check(err)
+					backend, err := newBackend(p, world, 0)
+					util.Check(err)
+
+					//run an individual bench
+					start := time.Now()
+					bareProcessTurns(backend, turn)
+					elapsed := time.Since(start)
+					backend.close()
+
+					partition := "strips"
+					if tiles {
+						partition = "tiles"
+					}
+					cells := "all"
+					if p.ActiveTiles {
+						cells = "active"
+					}
+
+					//append to result
+					f, _ := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+					defer f.Close()
+					f.WriteString(fmt.Sprintf("%d, %d, %d, %f, %v, %v, %v\n", turn, size, thread, elapsed.Seconds(), p.Engine, partition, cells))
+				}
 			}
 		}
 	}
 }
 
-func bareProcessTurns(active_world World, other_world World, turns int, threads int) {
+func bareProcessTurns(backend backend, turns int) {
 	for i := 0; i < turns; i++ {
 		//do a bare turn
-		active_world.bareProcessOneTurn(other_world, threads)
-		//swap active and other
-		temp := active_world
-		active_world = other_world
-		other_world = temp
+		backend.bareProcessOneTurn()
 	}
 }
@@ -0,0 +1,250 @@
+package gol
+
+import (
+	"math/bits"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// bitWorld packs 64 cells into each word.
+// Bit i of word j in a row holds the cell at x = 64*j + i, bits past the width of the world are always 0.
+type bitWorld struct {
+	words      []uint64
+	stride     int
+	dimensions Dimensions
+}
+
+func newBitWorld(dimensions Dimensions) bitWorld {
+	stride := (dimensions.width + 63) / 64
+	return bitWorld{
+		words:      make([]uint64, stride*dimensions.height),
+		stride:     stride,
+		dimensions: dimensions,
+	}
+}
+
+func (world World) toBits() bitWorld {
+	packed := newBitWorld(world.dimensions)
+	for y := 0; y < world.dimensions.height; y++ {
+		row := packed.row(y)
+		for x := 0; x < world.dimensions.width; x++ {
+			if world.world[y][x] == alive {
+				row[x/64] |= 1 << uint(x%64)
+			}
+		}
+	}
+	return packed
+}
+
+func (packed bitWorld) toWorld() World {
+	world := newWorld(packed.dimensions)
+	for y := 0; y < packed.dimensions.height; y++ {
+		row := packed.row(y)
+		for x := 0; x < packed.dimensions.width; x++ {
+			if row[x/64]&(1<<uint(x%64)) != 0 {
+				world.world[y][x] = alive
+			}
+		}
+	}
+	return world
+}
+
+func (packed bitWorld) row(y int) []uint64 {
+	return packed.words[y*packed.stride : (y+1)*packed.stride]
+}
+
+// lastMask returns the bits of the last word in each row which hold cells.
+func (packed bitWorld) lastMask() uint64 {
+	if packed.dimensions.width%64 == 0 {
+		return ^uint64(0)
+	}
+	return 1<<uint(packed.dimensions.width%64) - 1
+}
+
+// bitBackend evolves a pair of bit worlds, swapping them after every turn.
+type bitBackend struct {
+	active  bitWorld
+	other   bitWorld
+	workers *pool
+	// flips are the cells changed by each worker in the current turn
+	flips    []*flips
+	rule     Rule
+	topology Topology
+	// empty is a row of dead cells used beyond the edges of a plane
+	empty []uint64
+}
+
+func newBitBackend(world World, threads int, rule Rule, topology Topology, batch bool) *bitBackend {
+	active := world.toBits()
+	workers := newPool(threads, get_strips(threads, world.dimensions))
+	return &bitBackend{
+		active:   active,
+		other:    newBitWorld(world.dimensions),
+		workers:  workers,
+		flips:    newFlips(workers.threads(), rule, batch),
+		rule:     rule,
+		topology: topology,
+		empty:    make([]uint64, active.stride),
+	}
+}
+
+func (b *bitBackend) sendInitialCellFlips(events chan<- Event, CompletedTurns int) {
+	all := startFlips(b.flips[:1], events, CompletedTurns)
+	for y := 0; y < b.active.dimensions.height; y++ {
+		for j, word := range b.active.row(y) {
+			addWordFlips(word, j, y, all[0])
+		}
+	}
+	sendFlips(all)
+}
+
+func (b *bitBackend) processTurns(events chan<- Event, CompletedTurns int, remaining int) (int, error) {
+	all := startFlips(b.flips, events, CompletedTurns)
+	b.processOneTurnWithThreads(all)
+	sendFlips(all)
+	b.active, b.other = b.other, b.active
+	return 1, nil
+}
+
+func (b *bitBackend) bareProcessOneTurn() {
+	b.processOneTurnWithThreads(nil)
+	b.active, b.other = b.other, b.active
+}
+
+func (b *bitBackend) world() World {
+	return b.active.toWorld()
+}
+
+func (b *bitBackend) close() {
+	b.workers.close()
+}
+
+// processOneTurnWithThreads writes the next turn into other, recording the cells changed by each worker in its flips unless all is nil.
+func (b *bitBackend) processOneTurnWithThreads(all []*flips) {
+	b.workers.run(func(worker int, _, range_y Range) {
+		f := workerFlips(all, worker)
+		for y := range_y.start; y < range_y.end; y++ {
+			b.processRow(y, f)
+		}
+	})
+}
+
+func (b *bitBackend) processRow(y int, f *flips) {
+	height := b.active.dimensions.height
+
+	var up, down []uint64
+	if b.topology.Surface == Plane && y == 0 {
+		up = b.empty
+	} else {
+		up = b.active.row(wrap(y-1, height))
+	}
+	if b.topology.Surface == Plane && y == height-1 {
+		down = b.empty
+	} else {
+		down = b.active.row(wrap(y+1, height))
+	}
+	mid := b.active.row(y)
+	next := b.other.row(y)
+
+	for j := range mid {
+		//each neighbour is one bit per cell, summed in parallel into the 4 bit count s3 s2 s1 s0
+		s0, s1, s2, s3 := countBits(
+			b.west(up, j), up[j], b.east(up, j),
+			b.west(mid, j), b.east(mid, j),
+			b.west(down, j), down[j], b.east(down, j),
+		)
+
+		var born, survives uint64
+		for n := 0; n <= 8; n++ {
+			if b.rule.birth[n] || b.rule.survival[n] {
+				matches := matchCount(n, s0, s1, s2, s3)
+				if b.rule.birth[n] {
+					born |= matches
+				}
+				if b.rule.survival[n] {
+					survives |= matches
+				}
+			}
+		}
+
+		next[j] = (mid[j] & survives) | (^mid[j] & born)
+		if j == len(mid)-1 {
+			next[j] &= b.active.lastMask()
+		}
+
+		if f != nil {
+			addWordFlips(mid[j]^next[j], j, y, f)
+		}
+	}
+}
+
+// west returns word j of row shifted so each bit holds the cell to its west.
+func (b *bitBackend) west(row []uint64, j int) uint64 {
+	var carry uint64
+	if j > 0 {
+		carry = row[j-1] >> 63
+	} else if b.topology.Surface == Torus {
+		last := b.active.dimensions.width - 1
+		carry = row[last/64] >> uint(last%64) & 1
+	}
+
+	shifted := row[j]<<1 | carry
+	if j == len(row)-1 {
+		shifted &= b.active.lastMask()
+	}
+	return shifted
+}
+
+// east returns word j of row shifted so each bit holds the cell to its east.
+func (b *bitBackend) east(row []uint64, j int) uint64 {
+	var carry uint64
+	if j < len(row)-1 {
+		carry = row[j+1] << 63
+	} else if b.topology.Surface == Torus {
+		last := b.active.dimensions.width - 1
+		carry = (row[0] & 1) << uint(last%64)
+	}
+
+	return row[j]>>1 | carry
+}
+
+// countBits adds 8 words bit by bit using full adders, returning each bit of the 4 bit sums.
+func countBits(n0, n1, n2, n3, n4, n5, n6, n7 uint64) (s0, s1, s2, s3 uint64) {
+	onesA, twosA := fullAdd(n0, n1, n2)
+	onesB, twosB := fullAdd(n3, n4, n5)
+	onesC, twosC := n6^n7, n6&n7
+
+	s0, twosD := fullAdd(onesA, onesB, onesC)
+
+	twos, foursA := fullAdd(twosA, twosB, twosC)
+	s1, foursB := twos^twosD, twos&twosD
+
+	s2, s3 = foursA^foursB, foursA&foursB
+	return
+}
+
+func fullAdd(a, b, c uint64) (sum, carry uint64) {
+	return a ^ b ^ c, (a & b) | (c & (a ^ b))
+}
+
+// matchCount returns the bits whose 4 bit sum s3 s2 s1 s0 is equal to n.
+func matchCount(n int, s0, s1, s2, s3 uint64) uint64 {
+	matches := ^uint64(0)
+	for i, s := range []uint64{s0, s1, s2, s3} {
+		if n&(1<<uint(i)) != 0 {
+			matches &= s
+		} else {
+			matches &= ^s
+		}
+	}
+	return matches
+}
+
+// addWordFlips records a flip for every set bit in word j of row y.
+func addWordFlips(flipped uint64, j, y int, f *flips) {
+	for flipped != 0 {
+		i := bits.TrailingZeros64(flipped)
+		flipped &= flipped - 1
+		f.add(util.Cell{X: 64*j + i, Y: y}, 255)
+	}
+}
@@ -0,0 +1,438 @@
+package gol
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// maxBufferedTurns is the number of turns the broker runs ahead of its controller.
+const maxBufferedTurns = 64
+
+// workerTimeout is how long a worker has to process a strip before the broker treats it as failed.
+const workerTimeout = 10 * time.Second
+
+// updatesTimeout is how long a request for updates waits for a turn to complete before returning none.
+const updatesTimeout = 10 * time.Millisecond
+
+// broker runs a simulation for a controller, fanning each turn out to the registered workers in strips of rows.
+// Turns are buffered until the controller collects them, unless the controller has detached,
+// in which case the simulation runs on until a controller attaches to it again.
+// A worker which fails or times out is dropped, and its strip is reassigned to another worker.
+type broker struct {
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	workers  []*remoteWorker
+	sim      *simulation
+	listener net.Listener
+	done     chan struct{}
+	// shutdown closes done and listener only once, however many controllers ask the broker to shut down
+	shutdown sync.Once
+}
+
+// remoteWorker is a worker registered with the broker.
+type remoteWorker struct {
+	address string
+	client  *rpc.Client
+}
+
+// simulation is the state of a world being evolved by the broker.
+type simulation struct {
+	id       int
+	world    World
+	rule     Rule
+	topology Topology
+	turns    int
+	// completed is the number of turns completed so far
+	completed int
+	// updates are the turns completed but not yet collected by the controller
+	updates []TurnUpdate
+	stopped bool
+	// detached is true while no controller is collecting updates
+	detached bool
+}
+
+// ServeBroker accepts workers and controllers on listener until it is shut down.
+func ServeBroker(listener net.Listener) error {
+	b := &broker{listener: listener, done: make(chan struct{})}
+	b.cond = sync.NewCond(&b.mutex)
+	server := rpc.NewServer()
+	err := server.RegisterName("Broker", b)
+	if err != nil {
+		return err
+	}
+
+	return serve(server, listener, b.done)
+}
+
+// Register adds the worker listening on the given address.
+func (b *broker) Register(req RegisterRequest, res *RegisterResponse) error {
+	client, err := rpc.Dial("tcp", req.Address)
+	if err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.workers = append(b.workers, &remoteWorker{address: req.Address, client: client})
+	res.Workers = len(b.workers)
+	b.cond.Broadcast()
+	return nil
+}
+
+// Start stops any running simulation and starts evolving the world in the request.
+func (b *broker) Start(req StartRequest, res *StartResponse) error {
+	rule, err := ParseRule(req.Rule)
+	if err != nil {
+		return err
+	}
+	topology, err := ParseTopology(req.Topology)
+	if err != nil {
+		return err
+	}
+	if len(req.World) != req.Height {
+		return errors.New("the world does not match its height")
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	id := 1
+	if b.sim != nil {
+		b.sim.stopped = true
+		id = b.sim.id + 1
+	}
+	b.sim = &simulation{
+		id:        id,
+		world:     World{world: req.World, dimensions: Dimensions{width: req.Width, height: req.Height}},
+		rule:      rule,
+		topology:  topology,
+		turns:     req.Turns,
+		completed: req.CompletedTurns,
+	}
+	res.ID = id
+	res.Workers = len(b.workers)
+	b.cond.Broadcast()
+
+	go b.simulate(b.sim)
+	return nil
+}
+
+// Updates returns the turns completed since the last request, waiting briefly for one if there are none.
+func (b *broker) Updates(req UpdatesRequest, res *UpdatesResponse) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	sim, err := b.simulation(req.ID)
+	if err != nil {
+		return err
+	}
+	if sim.detached {
+		return errors.New("the simulation has been detached from")
+	}
+
+	expired := false
+	timer := time.AfterFunc(updatesTimeout, func() {
+		b.mutex.Lock()
+		expired = true
+		b.cond.Broadcast()
+		b.mutex.Unlock()
+	})
+	defer timer.Stop()
+
+	for len(sim.updates) == 0 && !sim.stopped && !expired {
+		b.cond.Wait()
+	}
+	if sim.stopped && len(sim.updates) == 0 {
+		return errors.New("the simulation has been stopped")
+	}
+
+	n := len(sim.updates)
+	if req.Max > 0 && req.Max < n {
+		n = req.Max
+	}
+	res.Updates = sim.updates[:n]
+	sim.updates = sim.updates[n:]
+	b.cond.Broadcast()
+	return nil
+}
+
+// Stop stops the simulation if it is still running.
+func (b *broker) Stop(req StopRequest, res *StopResponse) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	sim, err := b.simulation(req.ID)
+	if err != nil {
+		return err
+	}
+	sim.stopped = true
+	res.CompletedTurns = sim.completed
+	b.cond.Broadcast()
+	return nil
+}
+
+// Detach lets the simulation run on without its controller, discarding its updates until a controller attaches.
+func (b *broker) Detach(req DetachRequest, res *DetachResponse) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	sim, err := b.simulation(req.ID)
+	if err != nil {
+		return err
+	}
+	sim.detached = true
+	sim.updates = nil
+	res.CompletedTurns = sim.completed
+	b.cond.Broadcast()
+	return nil
+}
+
+// Attach connects a controller to the detached simulation, returning its world as of the last completed turn.
+// Updates for the turns after it are buffered from then on.
+func (b *broker) Attach(req AttachRequest, res *AttachResponse) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	sim := b.sim
+	if sim == nil || sim.stopped {
+		return errors.New("no simulation is running on the broker")
+	}
+	if !sim.detached {
+		return errors.New("the simulation already has a controller")
+	}
+
+	sim.detached = false
+	res.ID = sim.id
+	res.Turns = sim.turns
+	res.CompletedTurns = sim.completed
+	res.Rule = sim.rule.String()
+	res.Topology = sim.topology.String()
+	res.Width = sim.world.dimensions.width
+	res.Height = sim.world.dimensions.height
+	res.World = sim.world.world
+	return nil
+}
+
+// simulation returns the running simulation if it has the given id.
+func (b *broker) simulation(id int) (*simulation, error) {
+	if b.sim == nil || b.sim.id != id {
+		return nil, fmt.Errorf("simulation %d is not running on the broker", id)
+	}
+	return b.sim, nil
+}
+
+// Shutdown stops the running simulation and every worker, then the broker itself.
+func (b *broker) Shutdown(req ShutdownRequest, res *ShutdownResponse) error {
+	b.mutex.Lock()
+	if b.sim != nil {
+		b.sim.stopped = true
+		res.CompletedTurns = b.sim.completed
+	}
+	workers := b.workers
+	b.workers = nil
+	b.cond.Broadcast()
+	b.mutex.Unlock()
+
+	for _, w := range workers {
+		_ = w.client.Call("Worker.Shutdown", ShutdownRequest{}, &ShutdownResponse{})
+		w.client.Close()
+	}
+
+	var err error
+	b.shutdown.Do(func() {
+		close(b.done)
+		err = b.listener.Close()
+	})
+	return err
+}
+
+// simulate evolves sim until it completes its turns or is stopped,
+// waiting while there are no workers or the controller has not collected enough turns.
+func (b *broker) simulate(sim *simulation) {
+	for {
+		b.mutex.Lock()
+		for !sim.stopped && sim.completed < sim.turns && (len(b.workers) == 0 || (!sim.detached && len(sim.updates) >= maxBufferedTurns)) {
+			b.cond.Wait()
+		}
+		if sim.stopped || sim.completed == sim.turns {
+			b.mutex.Unlock()
+			return
+		}
+		world := sim.world
+		b.mutex.Unlock()
+
+		next, update, ok := b.processTurn(sim, world)
+		if !ok {
+			return
+		}
+
+		b.mutex.Lock()
+		update.CompletedTurns = sim.completed
+		sim.world = next
+		sim.completed++
+		if !sim.detached {
+			sim.updates = append(sim.updates, update)
+		}
+		b.cond.Broadcast()
+		b.mutex.Unlock()
+	}
+}
+
+// processTurn sends a strip of the world to each worker, returning the next turn and the cells that changed.
+// If a worker fails, the strips it did not return are processed again from the world of the last completed turn
+// by the workers remaining, waiting for one to register if there are none.
+// It returns false if the simulation is stopped before the turn completes.
+func (b *broker) processTurn(sim *simulation, world World) (World, TurnUpdate, bool) {
+	var update TurnUpdate
+	workers := b.waitForWorkers(sim)
+	if workers == nil {
+		return World{}, update, false
+	}
+
+	height := world.dimensions.height
+	strips := make([]Range, len(workers))
+	responses := make([]StripResponse, len(workers))
+	pending := make([]int, len(workers))
+	for i := range strips {
+		strips[i] = get_sliced_range(i, len(workers), height)
+		pending[i] = i
+	}
+
+	for len(pending) > 0 {
+		failed := b.processStrips(world, sim, strips, responses, pending, workers, &update)
+		pending = failed
+		if len(pending) > 0 {
+			workers = b.waitForWorkers(sim)
+			if workers == nil {
+				return World{}, update, false
+			}
+		}
+	}
+
+	next := World{world: make([][]byte, 0, height), dimensions: world.dimensions}
+	for _, res := range responses {
+		next.world = append(next.world, res.Rows...)
+		update.Cells = append(update.Cells, res.Cells...)
+		update.Values = append(update.Values, res.Values...)
+	}
+	return next, update, true
+}
+
+// processStrips sends each pending strip to one of workers, returning the strips which failed.
+// Workers which fail are removed from the broker and recorded in update.
+func (b *broker) processStrips(world World, sim *simulation, strips []Range, responses []StripResponse, pending []int, workers []*remoteWorker, update *TurnUpdate) []int {
+	calls := make([]*rpc.Call, len(pending))
+	//a call which times out may still be decoding its reply, so each attempt has its own response
+	//which is only copied into responses once the call succeeds
+	replies := make([]StripResponse, len(pending))
+	for k, i := range pending {
+		//only the strip and the rows around it are sent, rather than the whole world to every worker
+		numbers := sim.topology.rowsRead(strips[i], sim.rule.radius, world.dimensions)
+		rows := make([][]byte, len(numbers))
+		for j, y := range numbers {
+			rows[j] = world.world[y]
+		}
+		req := StripRequest{
+			Rule:     sim.rule.String(),
+			Topology: sim.topology.String(),
+			Width:    world.dimensions.width,
+			Height:   world.dimensions.height,
+			Rows:     rows,
+			Numbers:  numbers,
+			Start:    strips[i].start,
+			End:      strips[i].end,
+		}
+		calls[k] = workers[k%len(workers)].client.Go("Worker.ProcessStrip", req, &replies[k], nil)
+	}
+
+	var failed []int
+	lost := make(map[*remoteWorker]bool)
+	deadline := time.Now().Add(workerTimeout)
+	for k, call := range calls {
+		w := workers[k%len(workers)]
+		var err error
+		select {
+		case <-call.Done:
+			err = call.Error
+		case <-time.After(time.Until(deadline)):
+			err = fmt.Errorf("no reply within %v", workerTimeout)
+		}
+		if err == nil {
+			responses[pending[k]] = replies[k]
+			continue
+		}
+
+		failed = append(failed, pending[k])
+		if !lost[w] {
+			lost[w] = true
+			remaining := b.removeWorker(w)
+			update.Failures = append(update.Failures, WorkerFailure{Address: w.address, Reason: err.Error(), Workers: remaining})
+		}
+	}
+	return failed
+}
+
+// waitForWorkers returns the workers registered, waiting for one if there are none.
+// It returns nil if the simulation is stopped first.
+func (b *broker) waitForWorkers(sim *simulation) []*remoteWorker {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for !sim.stopped && len(b.workers) == 0 {
+		b.cond.Wait()
+	}
+	if sim.stopped {
+		return nil
+	}
+	return append([]*remoteWorker(nil), b.workers...)
+}
+
+// removeWorker drops a failed worker, returning the number of workers remaining.
+func (b *broker) removeWorker(failed *remoteWorker) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for i, w := range b.workers {
+		if w == failed {
+			b.workers = append(b.workers[:i], b.workers[i+1:]...)
+			break
+		}
+	}
+	failed.client.Close()
+	return len(b.workers)
+}
+
+// serve answers requests on listener until done is closed,
+// then waits a moment for replies still being written.
+func serve(server *rpc.Server, listener net.Listener, done <-chan struct{}) error {
+	var wg sync.WaitGroup
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-done:
+				waitTimeout(&wg, time.Second)
+				return nil
+			default:
+				return err
+			}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.ServeConn(conn)
+		}()
+	}
+}
+
+// waitTimeout waits for wg, giving up after timeout.
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) {
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(timeout):
+	}
+}
@@ -0,0 +1,57 @@
+package gol
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// decodeCells returns the Plaintext pattern in data read from filename, in a box as wide as its longest row.
+// Lines starting with ! are comments, and every other line is a row of . for dead and O for alive cells.
+func decodeCells(filename string, data []byte) (pattern, error) {
+	var p pattern
+	//blank lines in the middle are empty rows, but those at the end are not
+	for _, line := range strings.Split(strings.TrimRight(string(data), " \t\r\n"), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		for x, c := range line {
+			switch c {
+			case '.':
+			case 'O', '*':
+				p.cells = append(p.cells, util.Cell{X: x, Y: p.height})
+			default:
+				return p, &HeaderError{Filename: filename, Reason: fmt.Sprintf("unexpected %q in the pattern", c)}
+			}
+		}
+		if len(line) > p.width {
+			p.width = len(line)
+		}
+		p.height++
+	}
+	return p, nil
+}
+
+// writeCells writes the world to writer as a Plaintext pattern with a row for every row of the world,
+// so the size of the world is kept when it is read back.
+func (world World) writeCells(writer io.Writer, name string) error {
+	buffered := bufio.NewWriter(writer)
+	fmt.Fprintf(buffered, "!Name: %v\n", name)
+	for _, row := range world.world {
+		line := make([]byte, len(row))
+		for x, cell := range row {
+			if cell == alive {
+				line[x] = 'O'
+			} else {
+				line[x] = '.'
+			}
+		}
+		buffered.Write(line)
+		buffered.WriteByte('\n')
+	}
+	return buffered.Flush()
+}
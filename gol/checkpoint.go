@@ -0,0 +1,136 @@
+package gol
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// defaultCheckpointTurns is the number of turns between checkpoints if Params.CheckpointTurns is left unset.
+const defaultCheckpointTurns = 1000
+
+// checkpoint is a world saved part way through a run along with the params needed to carry on from it.
+type checkpoint struct {
+	world World
+	// completed is the number of turns completed when the checkpoint was written
+	completed int
+	turns     int
+	threads   int
+	rule      Rule
+	topology  Topology
+	engine    EngineKind
+}
+
+// checkpoint_filename returns the file in dir that checkpoints of worlds the size of dimensions are written to.
+func checkpoint_filename(dir string, dimensions Dimensions) string {
+	return filepath.Join(dir, fmt.Sprintf("%vx%v.checkpoint.pgm", dimensions.width, dimensions.height))
+}
+
+// writeCheckpoint writes the world after completed turns of p to a pgm file in dir, returning its filename.
+// The params are recorded as comments in the header. The file is written alongside the last checkpoint
+// and renamed over it once complete, so a run stopped part way through a write leaves the last checkpoint intact.
+func writeCheckpoint(dir string, world World, completed int, p Params) (string, error) {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := ioutil.TempFile(dir, ".checkpoint-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+
+	err = world.writePgm(file,
+		"turn "+strconv.Itoa(completed),
+		"turns "+strconv.Itoa(p.Turns),
+		"threads "+strconv.Itoa(p.Threads),
+		"rule "+p.Rule.orDefault().String(),
+		"topology "+p.Topology.String(),
+		"engine "+p.Engine.String(),
+	)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	filename := checkpoint_filename(dir, world.dimensions)
+	return filename, os.Rename(file.Name(), filename)
+}
+
+// readCheckpoint reads a checkpoint written by writeCheckpoint.
+func readCheckpoint(filename string) (checkpoint, error) {
+	var c checkpoint
+	file, err := os.Open(filename)
+	if err != nil {
+		return c, err
+	}
+	defer file.Close()
+
+	image, err := util.ReadNetpbm(file)
+	if err != nil {
+		return c, fmt.Errorf("checkpoint %v: %v", filename, err)
+	}
+	c.world = worldOf(image)
+
+	//the params are recorded as comments
+	for _, comment := range image.Comments {
+		fields := strings.Fields(comment)
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "turn":
+			c.completed, err = strconv.Atoi(fields[1])
+		case "turns":
+			c.turns, err = strconv.Atoi(fields[1])
+		case "threads":
+			c.threads, err = strconv.Atoi(fields[1])
+		case "rule":
+			c.rule, err = ParseRule(fields[1])
+		case "topology":
+			c.topology, err = ParseTopology(fields[1])
+		case "engine":
+			c.engine, err = ParseEngineKind(fields[1])
+		}
+		if err != nil {
+			return c, fmt.Errorf("checkpoint %v: %v", filename, err)
+		}
+	}
+	return c, nil
+}
+
+// ResumeParams returns p updated with the image size, turns, threads, rule, topology and engine
+// of the checkpoint at p.Resume, or p unchanged if it is not resuming.
+func ResumeParams(p Params) (Params, error) {
+	if p.Resume == "" {
+		return p, nil
+	}
+	c, err := readCheckpoint(p.Resume)
+	if err != nil {
+		return p, err
+	}
+	return c.params(p), nil
+}
+
+// params returns p updated with the params recorded in the checkpoint.
+func (c checkpoint) params(p Params) Params {
+	p.ImageWidth = c.world.dimensions.width
+	p.ImageHeight = c.world.dimensions.height
+	p.Turns = c.turns
+	p.Threads = c.threads
+	p.Rule = c.rule
+	p.Topology = c.topology
+	p.Engine = c.engine
+	return p
+}
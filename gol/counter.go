@@ -0,0 +1,71 @@
+package gol
+
+// counter counts the alive neighbours of cells in a world under a rule and topology.
+// Rules using the 8 cells of the radius 1 Moore neighbourhood on a torus are counted directly,
+// anything else is counted from prefix sums over the world padded by the neighbourhood radius,
+// so each cell costs one subtraction per row of its neighbourhood rather than one read per neighbour.
+type counter struct {
+	world World
+	rule  Rule
+	// sums[y][i] is the number of alive cells in padded row y from x = -radius up to x = i-radius-1
+	sums [][]int32
+}
+
+func (world World) newCounter(rule Rule, topology Topology) counter {
+	return world.newStripCounter(rule, topology, Range{start: 0, end: world.dimensions.height})
+}
+
+// newStripCounter returns a counter for the cells in rows, which only reads the rows of the world
+// found by topology.rowsRead, so the rest may be left nil.
+func (world World) newStripCounter(rule Rule, topology Topology, rows Range) counter {
+	c := counter{world: world, rule: rule}
+	if !rule.isLargerThanLife() && topology.Surface == Torus {
+		return c
+	}
+
+	width := world.dimensions.width
+	radius := rule.radius
+	c.sums = make([][]int32, world.dimensions.height+2*radius)
+	for py := rows.start; py < rows.end+2*radius; py++ {
+		row := make([]int32, width+2*radius+1)
+		for px := 0; px < width+2*radius; px++ {
+			row[px+1] = row[px]
+			x, y, ok := topology.locate(px-radius, py-radius, world.dimensions)
+			if ok && world.world[y][x] == alive {
+				row[px+1]++
+			}
+		}
+		c.sums[py] = row
+	}
+
+	return c
+}
+
+func (c counter) count(x, y int) int {
+	if c.sums == nil {
+		return c.world.countNeigbours(x, y)
+	}
+
+	radius := c.rule.radius
+	neighbors := 0
+	for dy := -radius; dy <= radius; dy++ {
+		//the row reaches out radius cells either side for Moore and narrows into a diamond for von Neumann
+		reach := radius
+		if c.rule.neighbourhood == VonNeumann {
+			if dy < 0 {
+				reach += dy
+			} else {
+				reach -= dy
+			}
+		}
+
+		row := c.sums[y+radius+dy]
+		neighbors += int(row[x+radius+reach+1] - row[x+radius-reach])
+	}
+
+	if !c.rule.middle && c.world.world[y][x] == alive {
+		neighbors--
+	}
+
+	return neighbors
+}
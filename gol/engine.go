@@ -0,0 +1,185 @@
+package gol
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EngineKind selects how the world is stored and evolved.
+type EngineKind int
+
+const (
+	// ByteEngine stores one byte per cell and supports every rule and topology.
+	ByteEngine EngineKind = iota
+	// BitEngine packs 64 cells into each word and updates a whole word at once.
+	// It supports Life-like rules using the 8 cells of the Moore neighbourhood on a torus or plane.
+	BitEngine
+	// HashLifeEngine memoises the future of every square of the world in a quadtree,
+	// advancing by doubling powers of two turns at a time.
+	// It supports the same rules as BitEngine on a torus whose width and height are powers of two.
+	HashLifeEngine
+	// HaloEngine gives each worker its own strip of the world and a ghost row above and below it,
+	// exchanging the rows at the edges of the strips over channels every turn instead of sharing the world.
+	// It supports rules using the 8 cells of the Moore neighbourhood on a torus or plane.
+	HaloEngine
+)
+
+var engineNames = map[EngineKind]string{
+	ByteEngine:     "byte",
+	BitEngine:      "bit",
+	HashLifeEngine: "hashlife",
+	HaloEngine:     "halo",
+}
+
+// ParseEngineKind parses the name of an engine as returned by EngineKind.String.
+func ParseEngineKind(name string) (EngineKind, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for kind, kindName := range engineNames {
+		if name == kindName {
+			return kind, nil
+		}
+	}
+	return ByteEngine, fmt.Errorf("engine %q: expected one of byte, bit, hashlife or halo", name)
+}
+
+func (kind EngineKind) String() string {
+	return engineNames[kind]
+}
+
+// Set parses name into the engine kind, allowing an EngineKind to be used as a flag.Value.
+func (kind *EngineKind) Set(name string) error {
+	parsed, err := ParseEngineKind(name)
+	if err != nil {
+		return err
+	}
+	*kind = parsed
+	return nil
+}
+
+// backend is the state of a running simulation for one kind of engine.
+type backend interface {
+	// sendInitialCellFlips sends events for every cell that is not dead in the initial world,
+	// which is the world after CompletedTurns when resuming part way through a run.
+	sendInitialCellFlips(events chan<- Event, CompletedTurns int)
+	// processTurns evolves the world by at least one and at most remaining turns,
+	// sending events for every cell that changed and returning the number of turns completed.
+	processTurns(events chan<- Event, CompletedTurns int, remaining int) (int, error)
+	// bareProcessOneTurn evolves the world by one turn without sending any events.
+	bareProcessOneTurn()
+	// world returns the world as of the last completed turn.
+	world() World
+	// close stops any workers started by the backend, which cannot be used afterwards.
+	close()
+}
+
+// newBackend returns a backend for the engine, rule and topology in p which starts from the world after CompletedTurns.
+func newBackend(p Params, world World, CompletedTurns int) (backend, error) {
+	rule := p.Rule.orDefault()
+	topology := p.Topology
+	threads := p.Threads
+
+	if p.ActiveTiles && p.Engine != ByteEngine {
+		return nil, fmt.Errorf("the %v engine does not support active tiles", p.Engine)
+	}
+	if p.Tiles && p.Engine != ByteEngine {
+		return nil, fmt.Errorf("the %v engine does not support tiles", p.Engine)
+	}
+	if p.TileSize < 0 {
+		return nil, fmt.Errorf("tile size %d: expected a positive size", p.TileSize)
+	}
+
+	if p.Server != "" {
+		if p.Engine != ByteEngine || p.ActiveTiles || p.Tiles {
+			return nil, fmt.Errorf("the broker only supports the byte engine without active tiles or tiles")
+		}
+		return newRemoteBackend(p, world, rule, CompletedTurns, p.BatchFlips)
+	}
+
+	switch p.Engine {
+	case ByteEngine:
+		blocks := get_strips(threads, world.dimensions)
+		if p.Tiles {
+			size := p.TileSize
+			if size == 0 {
+				size = auto_tile_size(threads, world.dimensions)
+			}
+			blocks = get_tiles(size, world.dimensions)
+		}
+		workers := newPool(threads, blocks)
+		b := &byteBackend{
+			active:   world,
+			other:    newWorld(world.dimensions),
+			workers:  workers,
+			flips:    newFlips(workers.threads(), rule, p.BatchFlips),
+			rule:     rule,
+			topology: topology,
+		}
+		if p.ActiveTiles {
+			b.tracker = newActivity(world.dimensions, rule, topology)
+		}
+		return b, nil
+	case BitEngine:
+		if rule.isLargerThanLife() || rule.isGenerations() {
+			return nil, fmt.Errorf("the bit engine does not support the rule %v", rule)
+		}
+		if topology.Surface != Torus && topology.Surface != Plane {
+			return nil, fmt.Errorf("the bit engine does not support the topology %v", topology)
+		}
+		return newBitBackend(world, threads, rule, topology, p.BatchFlips), nil
+	case HashLifeEngine:
+		if topology.Surface != Torus {
+			return nil, fmt.Errorf("the hashlife engine does not support the topology %v", topology)
+		}
+		return newHashLifeBackend(world, rule, p.BatchFlips)
+	case HaloEngine:
+		if rule.isLargerThanLife() {
+			return nil, fmt.Errorf("the halo engine does not support the rule %v", rule)
+		}
+		if topology.Surface != Torus && topology.Surface != Plane {
+			return nil, fmt.Errorf("the halo engine does not support the topology %v", topology)
+		}
+		return newHaloBackend(world, threads, rule, topology, p.BatchFlips), nil
+	default:
+		return nil, fmt.Errorf("unknown engine %d", p.Engine)
+	}
+}
+
+// byteBackend evolves a pair of byte worlds, swapping them after every turn.
+type byteBackend struct {
+	active  World
+	other   World
+	workers *pool
+	// flips are the cells changed by each worker in the current turn
+	flips    []*flips
+	rule     Rule
+	topology Topology
+	// tracker is nil unless only tiles whose neighbourhood changed are recomputed
+	tracker *activity
+}
+
+func (b *byteBackend) sendInitialCellFlips(events chan<- Event, CompletedTurns int) {
+	all := startFlips(b.flips, events, CompletedTurns)
+	b.active.sendInitialCellFlips(b.workers, all)
+	sendFlips(all)
+}
+
+func (b *byteBackend) processTurns(events chan<- Event, CompletedTurns int, remaining int) (int, error) {
+	all := startFlips(b.flips, events, CompletedTurns)
+	b.active.processOneTurnWithThreads(b.other, b.workers, b.rule, b.topology, b.tracker, all)
+	sendFlips(all)
+	b.active, b.other = b.other, b.active
+	return 1, nil
+}
+
+func (b *byteBackend) bareProcessOneTurn() {
+	b.active.bareProcessOneTurn(b.other, b.workers, b.rule, b.topology, b.tracker)
+	b.active, b.other = b.other, b.active
+}
+
+func (b *byteBackend) world() World {
+	return b.active
+}
+
+func (b *byteBackend) close() {
+	b.workers.close()
+}
@@ -0,0 +1,73 @@
+package gol
+
+import "fmt"
+
+// The errors returned by RunContext when reading the input image or writing the output fails.
+
+// MissingImageError is returned when the input image cannot be opened.
+type MissingImageError struct {
+	Filename string
+	Err      error
+}
+
+func (err *MissingImageError) Error() string {
+	return fmt.Sprintf("input image %v: %v", err.Filename, err.Err)
+}
+
+func (err *MissingImageError) Unwrap() error {
+	return err.Err
+}
+
+// HeaderError is returned when the input image, or the image passed to Load, is not in a recognised format:
+// a Netpbm image without a P1, P2, P4 or P5 header with a maxval up to 65535, or a pattern which cannot be parsed.
+type HeaderError struct {
+	Filename string
+	Reason   string
+}
+
+func (err *HeaderError) Error() string {
+	if err.Filename == "" {
+		return fmt.Sprintf("input image: %v", err.Reason)
+	}
+	return fmt.Sprintf("input image %v: %v", err.Filename, err.Reason)
+}
+
+// PixelError is returned when the input image, or the image passed to Load, has a correct Netpbm header
+// but its pixel data ends early or holds a value the header does not allow.
+type PixelError struct {
+	Filename string
+	Reason   string
+}
+
+func (err *PixelError) Error() string {
+	if err.Filename == "" {
+		return fmt.Sprintf("input image: incorrect pixel data: %v", err.Reason)
+	}
+	return fmt.Sprintf("input image %v: incorrect pixel data: %v", err.Filename, err.Reason)
+}
+
+// DimensionError is returned when the input image is not the size given by Params.ImageWidth and Params.ImageHeight.
+type DimensionError struct {
+	Filename      string
+	Width, Height int
+	// ImageWidth and ImageHeight are the size the image was expected to be
+	ImageWidth, ImageHeight int
+}
+
+func (err *DimensionError) Error() string {
+	return fmt.Sprintf("input image %v: expected %vx%v, got %vx%v", err.Filename, err.ImageWidth, err.ImageHeight, err.Width, err.Height)
+}
+
+// OutputError is returned when an output image cannot be written.
+type OutputError struct {
+	Filename string
+	Err      error
+}
+
+func (err *OutputError) Error() string {
+	return fmt.Sprintf("output image %v: %v", err.Filename, err.Err)
+}
+
+func (err *OutputError) Unwrap() error {
+	return err.Err
+}
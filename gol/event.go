@@ -52,9 +52,38 @@ type CellFlipped struct { // implements Event
 	Cell           util.Cell
 }
 
+// CellStateChanged is an Event notifying the GUI about a cell changing to a state other than its opposite.
+// This Event is sent instead of CellFlipped when running a Generations rule, where dying cells
+// step through decay states stored as intermediate grey levels.
+type CellStateChanged struct { // implements Event
+	CompletedTurns int
+	Cell           util.Cell
+	Value          byte
+}
+
+// CellsFlipped is an Event notifying the GUI about every cell that changed state in a turn at once.
+// This Event is sent once per turn, including for the cells alive when the image is loaded in,
+// instead of CellFlipped and CellStateChanged events when Params.BatchFlips is set.
+type CellsFlipped struct { // implements Event
+	CompletedTurns int
+	Cells          []util.Cell
+	// Values[i] is the new value of Cells[i] when running a Generations rule, and Values is nil otherwise
+	Values []byte
+}
+
+// WorkerFailed is an Event notifying the user that the broker lost a worker while running the world.
+// The broker recovers by processing the strip of the lost worker again on the workers remaining,
+// starting from the last turn it completed, so no turns are lost.
+type WorkerFailed struct { // implements Event
+	CompletedTurns int
+	Worker         string
+	Reason         string
+	Workers        int
+}
+
 // TurnComplete is an Event notifying the GUI about turn completion.
 // SDL will render a frame when this event is sent.
-// All CellFlipped events must be sent *before* TurnComplete.
+// All CellFlipped, CellStateChanged and CellsFlipped events must be sent *before* TurnComplete.
 type TurnComplete struct { // implements Event
 	CompletedTurns int
 }
@@ -114,6 +143,30 @@ func (event CellFlipped) GetCompletedTurns() int {
 	return event.CompletedTurns
 }
 
+func (event CellStateChanged) String() string {
+	return fmt.Sprintf("")
+}
+
+func (event CellStateChanged) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event CellsFlipped) String() string {
+	return fmt.Sprintf("")
+}
+
+func (event CellsFlipped) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
+func (event WorkerFailed) String() string {
+	return fmt.Sprintf("Worker %v failed (%v), continuing on %v workers", event.Worker, event.Reason, event.Workers)
+}
+
+func (event WorkerFailed) GetCompletedTurns() int {
+	return event.CompletedTurns
+}
+
 func (event TurnComplete) String() string {
 	return fmt.Sprintf("")
 }
@@ -0,0 +1,85 @@
+package gol
+
+import "uk.ac.bris.cs/gameoflife/util"
+
+// flips sends the events for the cells changed by one worker in a turn.
+// Unless batching, an event is sent for each cell as it changes. Otherwise the cells are collected
+// so workers never contend for the events channel, and sent as one CellsFlipped event by sendFlips
+// once every worker has finished the turn.
+type flips struct {
+	events         chan<- Event
+	rule           Rule
+	batch          bool
+	CompletedTurns int
+	cells          []util.Cell
+	values         []byte
+}
+
+// newFlips returns the flips of each of the workers of a backend.
+func newFlips(workers int, rule Rule, batch bool) []*flips {
+	all := make([]*flips, workers)
+	for i := range all {
+		all[i] = &flips{rule: rule, batch: batch}
+	}
+	return all
+}
+
+// startFlips prepares every flips for a turn, returning nil if events is nil so that no events are sent.
+// The cells collected in the last turn are discarded, keeping their storage.
+func startFlips(all []*flips, events chan<- Event, CompletedTurns int) []*flips {
+	if events == nil {
+		return nil
+	}
+	for _, f := range all {
+		f.events = events
+		f.CompletedTurns = CompletedTurns
+		f.cells = f.cells[:0]
+		f.values = f.values[:0]
+	}
+	return all
+}
+
+// workerFlips returns the flips of worker i, or nil if no events are being sent.
+func workerFlips(all []*flips, i int) *flips {
+	if all == nil {
+		return nil
+	}
+	return all[i]
+}
+
+// add records cell changing to value. Cells under a Generations rule do not simply toggle,
+// so they report their new value instead of flipping.
+func (f *flips) add(cell util.Cell, value byte) {
+	if !f.batch {
+		f.events <- cellChangedEvent(f.rule, f.CompletedTurns, cell, value)
+		return
+	}
+	f.cells = append(f.cells, cell)
+	if f.rule.isGenerations() {
+		f.values = append(f.values, value)
+	}
+}
+
+// sendFlips sends the cells collected by every worker as one CellsFlipped event, if batching.
+// The event is sent even if no cells changed, and gets its own copy of the cells, so the flips can be reused.
+func sendFlips(all []*flips) {
+	if len(all) == 0 || !all[0].batch {
+		return
+	}
+
+	count := 0
+	for _, f := range all {
+		count += len(f.cells)
+	}
+	event := CellsFlipped{CompletedTurns: all[0].CompletedTurns, Cells: make([]util.Cell, 0, count)}
+	if all[0].rule.isGenerations() {
+		event.Values = make([]byte, 0, count)
+	}
+	for _, f := range all {
+		event.Cells = append(event.Cells, f.cells...)
+		if event.Values != nil {
+			event.Values = append(event.Values, f.values...)
+		}
+	}
+	all[0].events <- event
+}
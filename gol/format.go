@@ -0,0 +1,241 @@
+package gol
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format is a file format worlds are read from and written to.
+type Format int
+
+const (
+	// PGM is a binary greyscale image with a byte for every cell.
+	// Images are read from any of the Netpbm formats, plain or binary bitmaps as well as greymaps.
+	PGM Format = iota
+	// RLE is the run length encoded pattern format shared by Golly and the LifeWiki,
+	// which only records the alive cells.
+	RLE
+	// Cells is the Plaintext format, with a row of . and O for every row of cells.
+	Cells
+	// Life105 lists blocks of rows of . and * along with the position of each block.
+	Life105
+	// Life106 lists the x y coordinates of every alive cell.
+	Life106
+	// Macrocell is the quadtree format used by Golly, which writes every repeated square once.
+	Macrocell
+	// PBM is a binary bitmap with a bit for every cell, set for alive cells.
+	PBM
+	// PNG is a greyscale image with a pixel for every cell.
+	// PNG, JPEG and GIF pictures are read by scaling them to the board rather than as a pixel for every cell.
+	PNG
+)
+
+var formatNames = map[Format]string{
+	PGM:       "pgm",
+	RLE:       "rle",
+	Cells:     "cells",
+	Life105:   "life105",
+	Life106:   "life106",
+	Macrocell: "mc",
+	PBM:       "pbm",
+	PNG:       "png",
+}
+
+// ParseFormat parses the name of a format as returned by Format.String.
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "."))
+	for format, formatName := range formatNames {
+		if name == formatName {
+			return format, nil
+		}
+	}
+	return PGM, fmt.Errorf("format %q: expected one of pgm, rle, cells, life105, life106, mc, pbm or png", name)
+}
+
+func (format Format) String() string {
+	return formatNames[format]
+}
+
+// Set parses name into the format, allowing a Format to be used as a flag.Value.
+func (format *Format) Set(name string) error {
+	parsed, err := ParseFormat(name)
+	if err != nil {
+		return err
+	}
+	*format = parsed
+	return nil
+}
+
+// extension returns the extension of files in the format, including the dot.
+// Both Life formats share the .lif extension.
+func (format Format) extension() string {
+	if format == Life105 || format == Life106 {
+		return ".lif"
+	}
+	return "." + format.String()
+}
+
+// detect_format returns the format of data from its content, or false if it is not recognised.
+func detect_format(data []byte) (Format, bool) {
+	text := strings.TrimLeft(string(data), " \t\r\n")
+	switch {
+	case strings.HasPrefix(text, "P"):
+		return PGM, true
+	case strings.HasPrefix(text, life105Header):
+		return Life105, true
+	case strings.HasPrefix(text, life106Header):
+		return Life106, true
+	case strings.HasPrefix(text, macrocellHeader):
+		return Macrocell, true
+	case strings.HasPrefix(text, "!"):
+		return Cells, true
+	}
+
+	//rle patterns may start with # comments, so the format is decided by the first other line
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "x") && strings.Contains(line, "="):
+			return RLE, true
+		case strings.Trim(line, ".O*") == "":
+			return Cells, true
+		}
+		break
+	}
+	return PGM, false
+}
+
+// readImage returns the world on a board the size of dimensions loaded from filename, as decoded by decodeImage.
+func readImage(filename string, dimensions Dimensions, offset_x, offset_y int, dither Dither) (World, Rule, error) {
+	data, ioError := ioutil.ReadFile(filename)
+	if ioError != nil {
+		return World{}, Rule{}, &MissingImageError{Filename: filename, Err: ioError}
+	}
+	return decodeImage(filename, data, dimensions, offset_x, offset_y, dither)
+}
+
+// decodeImage returns the world on a board the size of dimensions in data read from filename, whatever its format,
+// along with the rule recorded in it, or the zero Rule if the format does not record one.
+// A width or height of 0 in dimensions is taken from the image instead.
+// Patterns are placed with their top left corner at offset_x, offset_y, while Netpbm images must match dimensions exactly.
+// PNG, JPEG and GIF pictures are scaled to dimensions and converted to cells with dither.
+func decodeImage(filename string, data []byte, dimensions Dimensions, offset_x, offset_y int, dither Dither) (World, Rule, error) {
+	if isPicture(data) {
+		world, err := decodePicture(filename, data, dimensions, dither)
+		return world, Rule{}, err
+	}
+
+	format, ok := detect_format(data)
+	if !ok {
+		return World{}, Rule{}, &HeaderError{Filename: filename, Reason: "not an image or pattern in a recognised format"}
+	}
+	if format == PGM {
+		world, err := decodeNetpbm(filename, data)
+		if err != nil {
+			return World{}, Rule{}, err
+		}
+		if (dimensions.width != 0 && world.dimensions.width != dimensions.width) ||
+			(dimensions.height != 0 && world.dimensions.height != dimensions.height) {
+			return World{}, Rule{}, &DimensionError{
+				Filename:    filename,
+				Width:       world.dimensions.width,
+				Height:      world.dimensions.height,
+				ImageWidth:  dimensions.width,
+				ImageHeight: dimensions.height,
+			}
+		}
+		return world, Rule{}, nil
+	}
+
+	var p pattern
+	var err error
+	switch format {
+	case RLE:
+		p, err = decodeRle(filename, data)
+	case Cells:
+		p, err = decodeCells(filename, data)
+	case Life105:
+		p, err = decodeLife105(filename, data)
+	case Life106:
+		p, err = decodeLife106(filename, data)
+	case Macrocell:
+		p, err = decodeMacrocell(filename, data)
+	}
+	if err != nil {
+		return World{}, Rule{}, err
+	}
+	world, err := p.place(filename, dimensions, offset_x, offset_y)
+	return world, p.rule, err
+}
+
+// readInput returns the world loaded from the input image of p, along with the rule recorded in it,
+// or the zero Rule if the format does not record one. Soups are drawn rather than loaded.
+func readInput(p Params) (World, Rule, error) {
+	if p.Soup {
+		world, err := newSoup(p)
+		return world, Rule{}, err
+	}
+	//an input already read by InputParams is copied, as the engine takes over the world it is given
+	if p.input != nil && p.input.key == inputKeyOf(p) {
+		world := newWorld(p.input.world.dimensions)
+		for y := range world.world {
+			copy(world.world[y], p.input.world.world[y])
+		}
+		return world, p.input.rule, nil
+	}
+	dimensions := Dimensions{width: p.ImageWidth, height: p.ImageHeight}
+	return readImage(input_filename(p), dimensions, p.PatternX, p.PatternY, p.Dither)
+}
+
+// input is an input image or pattern read by InputParams, kept so the run it returns the params of does not read it again.
+type input struct {
+	key   inputKey
+	world World
+	rule  Rule
+}
+
+// inputKey is everything in Params an input is read with, so an input is only reused for the params it was read for.
+type inputKey struct {
+	filename           string
+	width, height      int
+	patternX, patternY int
+	dither             Dither
+}
+
+func inputKeyOf(p Params) inputKey {
+	return inputKey{
+		filename: input_filename(p),
+		width:    p.ImageWidth,
+		height:   p.ImageHeight,
+		patternX: p.PatternX,
+		patternY: p.PatternY,
+		dither:   p.Dither,
+	}
+}
+
+// writeFile creates filename along with its directory and writes to it with write,
+// returning an OutputError if it cannot be written.
+func writeFile(filename string, write func(writer io.Writer) error) error {
+	_ = os.MkdirAll(filepath.Dir(filename), os.ModePerm)
+
+	file, ioError := os.Create(filename)
+	if ioError != nil {
+		return &OutputError{Filename: filename, Err: ioError}
+	}
+	defer file.Close()
+
+	ioError = write(file)
+	if ioError == nil {
+		ioError = file.Sync()
+	}
+	if ioError != nil {
+		return &OutputError{Filename: filename, Err: ioError}
+	}
+	return nil
+}
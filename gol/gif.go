@@ -0,0 +1,212 @@
+package gol
+
+import (
+	"bufio"
+	"bytes"
+	"compress/lzw"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// gifBlockSize is the most bytes in one of the sub-blocks the compressed pixels of a frame are split into.
+const gifBlockSize = 255
+
+// maxGifValue is the largest width, height or delay a GIF can record, each being stored in 16 bits.
+const maxGifValue = 1<<16 - 1
+
+// GifRecorder draws the board of a run from its events, like the SDL window, and records it as the frames of an animated GIF.
+// Each frame is compressed as it is recorded, so only the compressed frames are kept until the GIF is saved.
+type GifRecorder struct {
+	// Every is the number of turns between frames, every turn if left unset.
+	// Engines which complete several turns at once are recorded at the first TurnComplete at least Every turns after the last frame.
+	Every int
+	// Scale is the width and height of each cell in pixels, 1 if left unset.
+	Scale int
+	// Delay is the time each frame is shown for in hundredths of a second.
+	Delay int
+
+	board World
+	// last is the turn of the last frame, or 0 if none has been recorded
+	last int
+	// frames are the compressed frames, each with the extension giving its delay, and width and height their size in pixels
+	frames bytes.Buffer
+	count  int
+	width  int
+	height int
+	// err is the first error recording a frame, after which no more frames are recorded
+	err error
+}
+
+// NewGifRecorder returns a recorder for a run of p, recording every turn at a scale of 1 until its fields are changed.
+func NewGifRecorder(p Params) *GifRecorder {
+	return &GifRecorder{board: newWorld(Dimensions{width: p.ImageWidth, height: p.ImageHeight})}
+}
+
+// Record forwards events to the returned channel, which is closed once events is, adding a frame to the GIF
+// after every Every turns and after the final turn.
+// The returned channel must be read until it is closed before the GIF is saved.
+func (r *GifRecorder) Record(events <-chan Event) <-chan Event {
+	forwarded := make(chan Event, cap(events))
+	go func() {
+		defer close(forwarded)
+		for event := range events {
+			r.add(event)
+			forwarded <- event
+		}
+	}()
+	return forwarded
+}
+
+// add updates the board from event, recording a frame if it completes a turn due one.
+func (r *GifRecorder) add(event Event) {
+	every := r.Every
+	if every <= 0 {
+		every = 1
+	}
+
+	switch e := event.(type) {
+	case CellFlipped:
+		r.flip(e.Cell.X, e.Cell.Y)
+	case CellStateChanged:
+		r.board.world[e.Cell.Y][e.Cell.X] = e.Value
+	case CellsFlipped:
+		for i, cell := range e.Cells {
+			if e.Values != nil {
+				r.board.world[cell.Y][cell.X] = e.Values[i]
+			} else {
+				r.flip(cell.X, cell.Y)
+			}
+		}
+	case TurnComplete:
+		if r.err == nil && e.CompletedTurns >= r.last+every {
+			r.err = r.frame(e.CompletedTurns)
+		}
+	case FinalTurnComplete:
+		if r.err == nil && (r.Frames() == 0 || e.CompletedTurns != r.last) {
+			r.err = r.frame(e.CompletedTurns)
+		}
+	}
+}
+
+func (r *GifRecorder) flip(x, y int) {
+	if r.board.world[y][x] == alive {
+		r.board.world[y][x] = dead
+	} else {
+		r.board.world[y][x] = alive
+	}
+}
+
+// frame records the board after turn as a frame, with a grey level for every value of a cell so cells are drawn
+// with the same levels as a pgm image.
+func (r *GifRecorder) frame(turn int) error {
+	scale := r.Scale
+	if scale <= 0 {
+		scale = 1
+	}
+	width, height := r.board.dimensions.width*scale, r.board.dimensions.height*scale
+	if width > maxGifValue || height > maxGifValue {
+		return fmt.Errorf("gif: %vx%v cells at a scale of %v is larger than %vx%v pixels",
+			r.board.dimensions.width, r.board.dimensions.height, scale, maxGifValue, maxGifValue)
+	}
+	if r.Delay < 0 || r.Delay > maxGifValue {
+		return fmt.Errorf("gif: delay %v: expected 0 to %v hundredths of a second", r.Delay, maxGifValue)
+	}
+
+	//graphic control extension with the delay, then an image descriptor covering the whole screen
+	header := []byte{0x21, 0xf9, 4, 0, 0, 0, 0, 0, 0x2c, 0, 0, 0, 0, 0, 0, 0, 0, 0, 8}
+	binary.LittleEndian.PutUint16(header[4:], uint16(r.Delay))
+	binary.LittleEndian.PutUint16(header[13:], uint16(width))
+	binary.LittleEndian.PutUint16(header[15:], uint16(height))
+
+	//the frame is only added once it has been compressed in full
+	var frame bytes.Buffer
+	frame.Write(header)
+	blocks := &gifBlockWriter{writer: &frame}
+	compressor := lzw.NewWriter(blocks, lzw.LSB, 8)
+	line := make([]byte, width)
+	for _, row := range r.board.world {
+		for x, cell := range row {
+			for i := 0; i < scale; i++ {
+				line[x*scale+i] = cell
+			}
+		}
+		for i := 0; i < scale; i++ {
+			if _, err := compressor.Write(line); err != nil {
+				return err
+			}
+		}
+	}
+	if err := compressor.Close(); err != nil {
+		return err
+	}
+	blocks.flush()
+	frame.WriteByte(0)
+
+	r.frames.Write(frame.Bytes())
+	r.width, r.height = width, height
+	r.count++
+	r.last = turn
+	return nil
+}
+
+// gifBlockWriter splits the compressed pixels of a frame into sub-blocks, each preceded by its length.
+type gifBlockWriter struct {
+	writer *bytes.Buffer
+	block  []byte
+}
+
+func (b *gifBlockWriter) Write(data []byte) (int, error) {
+	for _, c := range data {
+		b.block = append(b.block, c)
+		if len(b.block) == gifBlockSize {
+			b.flush()
+		}
+	}
+	return len(data), nil
+}
+
+func (b *gifBlockWriter) flush() {
+	if len(b.block) == 0 {
+		return
+	}
+	b.writer.WriteByte(byte(len(b.block)))
+	b.writer.Write(b.block)
+	b.block = b.block[:0]
+}
+
+// Frames returns the number of frames recorded so far.
+func (r *GifRecorder) Frames() int {
+	return r.count
+}
+
+// Save writes the frames recorded so far to writer as an animated GIF which loops forever.
+// It returns an error if a frame could not be recorded, or if none have been.
+func (r *GifRecorder) Save(writer io.Writer) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.count == 0 {
+		return errors.New("gif: no frames recorded")
+	}
+
+	w := bufio.NewWriter(writer)
+	screen := []byte{'G', 'I', 'F', '8', '9', 'a', 0, 0, 0, 0, 0xf7, 0, 0}
+	binary.LittleEndian.PutUint16(screen[6:], uint16(r.width))
+	binary.LittleEndian.PutUint16(screen[8:], uint16(r.height))
+	w.Write(screen)
+	//a global colour table of 256 grey levels
+	for i := 0; i < 256; i++ {
+		w.Write([]byte{byte(i), byte(i), byte(i)})
+	}
+	//loop forever
+	w.Write([]byte{0x21, 0xff, 11})
+	w.WriteString("NETSCAPE2.0")
+	w.Write([]byte{3, 1, 0, 0, 0})
+
+	w.Write(r.frames.Bytes())
+	w.WriteByte(0x3b)
+	//a bufio.Writer keeps the first error from writer, returning it from every later call
+	return w.Flush()
+}
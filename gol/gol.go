@@ -1,56 +1,201 @@
 package gol
 
 import (
+	"context"
 	"fmt"
 	"time"
+
+	"uk.ac.bris.cs/gameoflife/util"
 )
 
 // Params provides the details of how to run the Game of Life and which image to load
 // from the image/ folder
 type Params struct {
-	Turns       int
-	Threads     int
+	Turns   int
+	Threads int
+	// ImageWidth and ImageHeight are the size of the world, read from the header of InputPath if either is left unset.
 	ImageWidth  int
 	ImageHeight int
+	// InputPath is the Netpbm image, PNG, JPEG or GIF picture, or rle, cells, lif or mc pattern to load the world from,
+	// detected by its content, images/<width>x<height>.pgm if left unset. A pattern sets the rule unless Rule is set.
+	InputPath string
+	// Dither is how a picture loaded from InputPath is converted to alive and dead cells once scaled to the board,
+	// by threshold if left unset.
+	Dither Dither
+	// Soup fills the board at random instead of loading InputPath, recording the soup in the names of output images.
+	Soup bool
+	// Seed is the seed the soup is drawn from, so a run with the same soup params is reproducible.
+	Seed int64
+	// Density is the proportion of alive cells in the soup, 0.5 if left unset.
+	Density float64
+	// SoupWidth and SoupHeight are the size of the rectangle at the centre of the board the soup is drawn in,
+	// the whole board if left unset.
+	SoupWidth  int
+	SoupHeight int
+	// Symmetry is the symmetry the soup is made to have, none if left unset.
+	Symmetry Symmetry
+	// PatternX and PatternY are where the top left corner of a pattern is placed on the board.
+	PatternX int
+	PatternY int
+	// OutputDir is the directory output images are written to, out if left unset.
+	OutputDir string
+	// OutputFormat is the format output images are written in, pgm if left unset.
+	OutputFormat Format
+	// OutputName is the template for the names of output images, to which the extension of OutputFormat is added.
+	// It may contain {width}, {height}, {turn}, {rule}, {topology}, {timestamp}, the time the run started,
+	// and {soup}, the seed, density and symmetry of a soup.
+	// It is {width}x{height}x{turn} if left unset, followed by -{soup} for soups
+	// and -{topology} for worlds on anything other than a torus.
+	OutputName string
+	// Rule is the Life-like rule to evolve the world with, Conway's B3/S23 if left unset.
+	Rule Rule
+	// Topology is the surface the world is wrapped onto, a torus if left unset.
+	Topology Topology
+	// Engine selects how the world is stored and evolved, one byte per cell if left unset.
+	Engine EngineKind
+	// ActiveTiles makes the byte engine only recompute tiles of the world whose neighbourhood changed in the last turn.
+	ActiveTiles bool
+	// Tiles makes the byte engine share square tiles of the world between threads instead of a strip of rows each.
+	Tiles bool
+	// TileSize is the width and height of the tiles in cells, chosen from the image size and threads if left unset.
+	TileSize int
+	// Server is the address of a broker to run the world on instead of locally, e.g. 127.0.0.1:8030.
+	Server string
+	// Attach connects to the simulation left running on the broker at Server instead of starting a new one,
+	// taking its turns, rule and topology and carrying on from the turn it has reached.
+	Attach bool
+	// BatchFlips sends the cells changed in each turn as one CellsFlipped event, collected by each worker separately,
+	// instead of a CellFlipped or CellStateChanged event for every cell as it changes.
+	BatchFlips bool
+	// CheckpointDir is the directory to periodically save the world to, so the run can be resumed from it.
+	// No checkpoints are written if left unset.
+	CheckpointDir string
+	// CheckpointTurns is the number of turns between checkpoints, every 1000 turns if left unset.
+	CheckpointTurns int
+	// Resume is a checkpoint to carry on the run from, taking its image size, turns, threads, rule, topology and engine.
+	Resume string
+
+	// input is the input read by InputParams, if these params were returned by it
+	input *input
 }
 
-// Run starts the processing of Game of Life.
+// Run starts the processing of Game of Life, panicking if it cannot read the input or write the output.
 func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
-	dimensions := Dimensions{width: p.ImageWidth, height: p.ImageHeight}
+	util.Check(RunContext(context.Background(), p, events, keyPresses))
+}
 
-	active_world := readPgmImage(dimensions)
-	other_world := newWorld(dimensions)
+// RunContext starts the processing of Game of Life, returning once every turn is complete, q, k or d is pressed
+// or ctx is cancelled. Commands are the same keys as are pressed in the window. Events is always closed before it returns.
+// Detaching with d leaves the run carrying on on the broker, so returns without sending FinalTurnComplete or writing the output.
+// Once ctx is cancelled no more events are sent, so the caller can stop reading them, and ctx.Err() is returned
+// without writing the output.
+func RunContext(ctx context.Context, p Params, events chan<- Event, commands <-chan rune) error {
+	//events are sent on through a goroutine which drops them once ctx is cancelled,
+	//so the workers are never left blocked on a caller which has stopped reading
+	sent := make(chan Event)
+	forwarded := make(chan bool)
+	go forwardEvents(ctx, sent, events, forwarded)
 
-	//send initial cell flips
-	active_world.sendInitialCellFlips(p.Threads, events)
+	err := run(ctx, p, sent, commands)
+	close(sent)
+	<-forwarded
+	return err
+}
+
+// forwardEvents sends every event from sent to events until ctx is cancelled, then closes events once sent is closed.
+func forwardEvents(ctx context.Context, sent <-chan Event, events chan<- Event, forwarded chan<- bool) {
+	for event := range sent {
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+	}
+	close(events)
+	forwarded <- true
+}
+
+func run(ctx context.Context, p Params, events chan<- Event, commands <-chan rune) error {
+	engine, err := startEngine(p)
+	if err != nil {
+		return err
+	}
+	defer engine.Close()
+	p, start := engine.p, engine.Turn()
+	started := time.Now()
+
+	//send initial cell flips, or the whole world when attaching or resuming part way through
+	engine.sendInitialCellFlips(events)
+
+	checkpointTurns := p.CheckpointTurns
+	if checkpointTurns <= 0 {
+		checkpointTurns = defaultCheckpointTurns
+	}
+	checkpointed := start
 
 	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
 
 	quit := false
 
-	for i := 0; i < p.Turns && !quit; i++ {
+	for i := start; i < p.Turns && !quit; {
 		loopy := true
 		for loopy {
 			select {
+			case <-ctx.Done():
+				return ctx.Err()
 			case <-ticker.C:
 				//send the number of cells alive currently
-				CellsCount := len(active_world.to_cells())
+				CellsCount := engine.Population()
 				events <- AliveCellsCount{CompletedTurns: i, CellsCount: CellsCount}
-			case key := <-keyPresses:
+			case key, ok := <-commands:
+				if !ok {
+					//a closed channel is always ready, so stop selecting on it once the caller has closed it
+					commands = nil
+					continue
+				}
 				switch key {
 				case 's':
 					println("Generating Output File with Current State")
-					active_world.writePgmImage(out_filename(active_world, i))
+					if _, err := writeOutput(engine.world(), i, p, started); err != nil {
+						return err
+					}
 				case 'q':
 					println("Generating Output File with Current State and terminating")
-					active_world.writePgmImage(out_filename(active_world, i))
+					if _, err := writeOutput(engine.world(), i, p, started); err != nil {
+						return err
+					}
+					quit = true
+				case 'k':
+					println("Generating Output File with Current State and shutting down")
+					if _, err := writeOutput(engine.world(), i, p, started); err != nil {
+						return err
+					}
+					if remote, ok := engine.backend.(*remoteBackend); ok {
+						if err := remote.shutdownServers(); err != nil {
+							return err
+						}
+					}
 					quit = true
+				case 'd':
+					if remote, ok := engine.backend.(*remoteBackend); ok {
+						println("Detaching from the broker, which carries on from turn: ", i)
+						//the run is not complete, so neither the final turn nor the output are reported
+						return remote.detach()
+					}
 				case 'p':
 					println("Pausing execution on execution of turn: ", i)
-					for key := range keyPresses {
-						if key == 'p' {
-							println("Continuing")
-							break
+					for paused := true; paused; {
+						select {
+						case <-ctx.Done():
+							return ctx.Err()
+						case key, ok := <-commands:
+							if !ok {
+								commands = nil
+							}
+							if !ok || key == 'p' {
+								println("Continuing")
+								paused = false
+							}
 						}
 					}
 				}
@@ -58,28 +203,80 @@ func Run(p Params, events chan<- Event, keyPresses <-chan rune) {
 				loopy = false
 			}
 		}
+		if quit {
+			break
+		}
+
+		//do a turn, or several at once for engines which can jump ahead
+		turns, err := engine.processTurns(events, p.Turns-i)
+		if err != nil {
+			return err
+		}
+		i += turns
 
-		//do a turn
-		active_world.processOneTurnWithThreads(other_world, p.Threads, events, i)
-		//swap active and other
-		temp := active_world
-		active_world = other_world
-		other_world = temp
+		events <- TurnComplete{CompletedTurns: i}
 
-		events <- TurnComplete{CompletedTurns: i + 1}
+		if p.CheckpointDir != "" && i-checkpointed >= checkpointTurns {
+			if _, err := writeCheckpoint(p.CheckpointDir, engine.world(), i, p); err != nil {
+				return err
+			}
+			checkpointed = i
+		}
 
 	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 
-	events <- FinalTurnComplete{CompletedTurns: p.Turns, Alive: active_world.to_cells()}
+	events <- FinalTurnComplete{CompletedTurns: p.Turns, Alive: engine.Alive()}
 
-	filename := out_filename(active_world, p.Turns)
-	active_world.writePgmImage(filename)
+	filename, err := writeOutput(engine.world(), p.Turns, p, started)
+	if err != nil {
+		return err
+	}
 
 	events <- ImageOutputComplete{CompletedTurns: p.Turns, Filename: filename}
-
-	close(events)
+	return nil
 }
 
-func out_filename(world World, turns int) string {
-	return "out/" + fmt.Sprintf("%vx%vx%v.pgm", world.dimensions.width, world.dimensions.height, turns)
+// startEngine returns the engine to run p on, with p updated with the params of the simulation
+// being attached to or resumed, and starting from the turn it has reached.
+func startEngine(p Params) (*Engine, error) {
+	if p.Attach && p.Resume != "" {
+		return nil, fmt.Errorf("cannot resume from a checkpoint while attaching to a broker")
+	}
+
+	if p.Attach {
+		remote, simParams, completed, err := attachRemoteBackend(p)
+		if err != nil {
+			return nil, err
+		}
+		return &Engine{p: simParams, backend: remote, turn: completed}, nil
+	}
+
+	var world World
+	engine := &Engine{p: p}
+	if p.Resume != "" {
+		c, err := readCheckpoint(p.Resume)
+		if err != nil {
+			return nil, err
+		}
+		world, engine.p, engine.turn = c.world, c.params(p), c.completed
+	} else {
+		var rule Rule
+		var err error
+		world, rule, err = readInput(p)
+		if err != nil {
+			return nil, err
+		}
+		engine.p.ImageWidth, engine.p.ImageHeight = world.dimensions.width, world.dimensions.height
+		if p.Rule.birth == nil {
+			engine.p.Rule = rule
+		}
+	}
+
+	if err := engine.start(world); err != nil {
+		return nil, err
+	}
+	return engine, nil
 }
@@ -0,0 +1,206 @@
+package gol
+
+import "uk.ac.bris.cs/gameoflife/util"
+
+// haloBackend splits the world into strips of rows, each owned by a worker goroutine which shares no memory with the others.
+// Every worker keeps a ghost row above and below its strip, and before each turn sends its first and last rows
+// to the workers above and below it over channels to fill in their ghost rows.
+type haloBackend struct {
+	dimensions Dimensions
+	rule       Rule
+	workers    []chan haloRequest
+	// flips are the cells changed by each worker in the current turn
+	flips []*flips
+	done  chan bool
+}
+
+// haloRequest asks a worker to process a turn, recording the cells changed in flips unless it is nil,
+// or to reply with a copy of its strip if snapshot is not nil.
+type haloRequest struct {
+	flips    *flips
+	snapshot chan<- [][]byte
+}
+
+type haloWorker struct {
+	// rows is the strip with a ghost row at each end, so rows[1] is row offset of the world
+	rows     [][]byte
+	next     [][]byte
+	offset   int
+	rule     Rule
+	topology Topology
+	requests <-chan haloRequest
+	done     chan<- bool
+	// the channels to the workers above and below, nil at the edges of a plane
+	sendUp    chan<- []byte
+	sendDown  chan<- []byte
+	fromAbove <-chan []byte
+	fromBelow <-chan []byte
+}
+
+func newHaloBackend(world World, threads int, rule Rule, topology Topology, batch bool) *haloBackend {
+	height := world.dimensions.height
+	if threads > height {
+		threads = height
+	}
+	h := &haloBackend{
+		dimensions: world.dimensions,
+		rule:       rule,
+		flips:      newFlips(threads, rule, batch),
+		done:       make(chan bool),
+	}
+
+	//upward[i] carries the first row of worker i to the worker above, downward[i] its last row to the worker below
+	upward := make([]chan []byte, threads)
+	downward := make([]chan []byte, threads)
+	for i := 0; i < threads; i++ {
+		upward[i] = make(chan []byte, 1)
+		downward[i] = make(chan []byte, 1)
+	}
+
+	for i := 0; i < threads; i++ {
+		range_y := get_sliced_range(i, threads, height)
+		requests := make(chan haloRequest)
+		h.workers = append(h.workers, requests)
+
+		w := &haloWorker{
+			rows:     make([][]byte, range_y.end-range_y.start+2),
+			next:     make([][]byte, range_y.end-range_y.start+2),
+			offset:   range_y.start,
+			rule:     rule,
+			topology: topology,
+			requests: requests,
+			done:     h.done,
+		}
+		for y := range w.rows {
+			w.rows[y] = make([]byte, world.dimensions.width)
+			w.next[y] = make([]byte, world.dimensions.width)
+		}
+		for y := range_y.start; y < range_y.end; y++ {
+			copy(w.rows[y-range_y.start+1], world.world[y])
+		}
+
+		if topology.Surface != Plane || i > 0 {
+			w.sendUp = upward[i]
+			w.fromAbove = downward[wrap(i-1, threads)]
+		}
+		if topology.Surface != Plane || i < threads-1 {
+			w.sendDown = downward[i]
+			w.fromBelow = upward[wrap(i+1, threads)]
+		}
+
+		go w.work()
+	}
+
+	return h
+}
+
+func (w *haloWorker) work() {
+	for request := range w.requests {
+		if request.snapshot != nil {
+			strip := make([][]byte, len(w.rows)-2)
+			for y := range strip {
+				strip[y] = append([]byte(nil), w.rows[y+1]...)
+			}
+			request.snapshot <- strip
+			continue
+		}
+
+		w.exchange()
+		for y := 1; y < len(w.rows)-1; y++ {
+			w.processRow(y, request.flips)
+		}
+		w.rows, w.next = w.next, w.rows
+		w.done <- true
+	}
+}
+
+// exchange sends copies of the first and last rows of the strip to the neighbouring workers,
+// then fills the ghost rows with the rows they sent back.
+func (w *haloWorker) exchange() {
+	last := len(w.rows) - 1
+	if w.sendUp != nil {
+		w.sendUp <- append([]byte(nil), w.rows[1]...)
+	}
+	if w.sendDown != nil {
+		w.sendDown <- append([]byte(nil), w.rows[last-1]...)
+	}
+	if w.fromAbove != nil {
+		w.rows[0] = <-w.fromAbove
+	}
+	if w.fromBelow != nil {
+		w.rows[last] = <-w.fromBelow
+	}
+}
+
+func (w *haloWorker) processRow(y int, f *flips) {
+	width := len(w.rows[y])
+	for x := 0; x < width; x++ {
+		neighbours := 0
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if dx == 0 && dy == 0 {
+					continue
+				}
+				nx := x + dx
+				if w.topology.Surface == Plane && (nx < 0 || nx >= width) {
+					continue
+				}
+				if w.rows[y+dy][wrap(nx, width)] == alive {
+					neighbours++
+				}
+			}
+		}
+
+		w.next[y][x] = w.rule.next(w.rows[y][x], neighbours)
+		if f != nil && w.next[y][x] != w.rows[y][x] {
+			f.add(util.Cell{X: x, Y: w.offset + y - 1}, w.next[y][x])
+		}
+	}
+}
+
+func (h *haloBackend) sendInitialCellFlips(events chan<- Event, CompletedTurns int) {
+	world := h.world()
+	all_x := Range{start: 0, end: h.dimensions.width}
+	all_y := Range{start: 0, end: h.dimensions.height}
+	all := startFlips(h.flips[:1], events, CompletedTurns)
+	world.partialSendInitialCellFlips(all_x, all_y, all[0])
+	sendFlips(all)
+}
+
+func (h *haloBackend) processTurns(events chan<- Event, CompletedTurns int, remaining int) (int, error) {
+	all := startFlips(h.flips, events, CompletedTurns)
+	h.processOneTurn(all)
+	sendFlips(all)
+	return 1, nil
+}
+
+func (h *haloBackend) bareProcessOneTurn() {
+	h.processOneTurn(nil)
+}
+
+// processOneTurn has every worker process its strip, recording the cells changed by each in its flips unless all is nil.
+func (h *haloBackend) processOneTurn(all []*flips) {
+	for i, requests := range h.workers {
+		requests <- haloRequest{flips: workerFlips(all, i)}
+	}
+	for range h.workers {
+		<-h.done
+	}
+}
+
+// world collects a copy of every strip from the workers.
+func (h *haloBackend) world() World {
+	world := World{dimensions: h.dimensions}
+	for _, requests := range h.workers {
+		snapshot := make(chan [][]byte)
+		requests <- haloRequest{snapshot: snapshot}
+		world.world = append(world.world, <-snapshot...)
+	}
+	return world
+}
+
+func (h *haloBackend) close() {
+	for _, requests := range h.workers {
+		close(requests)
+	}
+}
@@ -0,0 +1,291 @@
+package gol
+
+import (
+	"fmt"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// maxHashLifeNodes is the number of nodes after which the caches are cleared
+// and the current world is rebuilt, bounding the memory used by long runs.
+const maxHashLifeNodes = 1 << 22
+
+// node is a square of 2^level cells in a HashLife quadtree.
+// Nodes are immutable and shared, so two nodes with the same contents are the same pointer.
+type node struct {
+	nw, ne, sw, se *node
+	level          int
+	population     int
+}
+
+type quad struct {
+	nw, ne, sw, se *node
+}
+
+type resultKey struct {
+	node *node
+	step int
+}
+
+// hashLifeBackend evolves a torus by building a quadtree of the world tiled infinitely in every direction,
+// which lets it advance 2^j turns at a time by memoising the future of every square it has seen.
+// The width and height of the world must be powers of two so the tiling lines up with the quadtree.
+type hashLifeBackend struct {
+	rule       Rule
+	dimensions Dimensions
+	flips      []*flips
+	nodes      map[quad]*node
+	results    map[resultKey]*node
+	dead       *node
+	alive      *node
+	// root is the smallest square node containing whole copies of the world, starting with the world itself
+	root *node
+	// tiles[i] is the tiling of the world at root.level+i
+	tiles []*node
+	// step is the log2 of the number of turns to advance next, doubling each time up to the turns remaining
+	step int
+}
+
+func newHashLifeBackend(world World, rule Rule, batch bool) (*hashLifeBackend, error) {
+	if rule.isLargerThanLife() || rule.isGenerations() {
+		return nil, fmt.Errorf("the hashlife engine does not support the rule %v", rule)
+	}
+	width, height := world.dimensions.width, world.dimensions.height
+	if width&(width-1) != 0 || height&(height-1) != 0 {
+		return nil, fmt.Errorf("the hashlife engine needs power of two dimensions, got %vx%v", width, height)
+	}
+
+	h := &hashLifeBackend{rule: rule, dimensions: world.dimensions, flips: newFlips(1, rule, batch)}
+	h.reset(world)
+	return h, nil
+}
+
+// reset clears the caches and rebuilds the tiling from world.
+func (h *hashLifeBackend) reset(world World) {
+	h.nodes = make(map[quad]*node)
+	h.results = make(map[resultKey]*node)
+	h.dead = &node{level: 0, population: 0}
+	h.alive = &node{level: 0, population: 1}
+
+	size, level := 1, 0
+	for size < h.dimensions.width || size < h.dimensions.height {
+		size *= 2
+		level++
+	}
+	h.setRoot(h.build(world, 0, 0, level))
+}
+
+func (h *hashLifeBackend) setRoot(root *node) {
+	h.root = root
+	h.tiles = []*node{root}
+}
+
+// build returns the node of the given level with its top-left cell at x, y of the tiled world.
+func (h *hashLifeBackend) build(world World, x, y, level int) *node {
+	if level == 0 {
+		if world.world[y%h.dimensions.height][x%h.dimensions.width] == alive {
+			return h.alive
+		}
+		return h.dead
+	}
+
+	half := 1 << uint(level-1)
+	return h.join(
+		h.build(world, x, y, level-1),
+		h.build(world, x+half, y, level-1),
+		h.build(world, x, y+half, level-1),
+		h.build(world, x+half, y+half, level-1),
+	)
+}
+
+// join returns the unique node made of four children.
+func (h *hashLifeBackend) join(nw, ne, sw, se *node) *node {
+	key := quad{nw, ne, sw, se}
+	if n, ok := h.nodes[key]; ok {
+		return n
+	}
+
+	n := &node{
+		nw: nw, ne: ne, sw: sw, se: se,
+		level:      nw.level + 1,
+		population: nw.population + ne.population + sw.population + se.population,
+	}
+	h.nodes[key] = n
+	return n
+}
+
+// tile returns the tiling of the world at level, which must be at least the level of the root.
+func (h *hashLifeBackend) tile(level int) *node {
+	for len(h.tiles) <= level-h.root.level {
+		t := h.tiles[len(h.tiles)-1]
+		h.tiles = append(h.tiles, h.join(t, t, t, t))
+	}
+	return h.tiles[level-h.root.level]
+}
+
+// centre returns the node of half the size at the centre of n.
+func (h *hashLifeBackend) centre(n *node) *node {
+	return h.join(n.nw.se, n.ne.sw, n.sw.ne, n.se.nw)
+}
+
+// result returns the centre of n, which must be at least level 2, after 2^step turns.
+// step must be at most n.level-2.
+func (h *hashLifeBackend) result(n *node, step int) *node {
+	key := resultKey{n, step}
+	if r, ok := h.results[key]; ok {
+		return r
+	}
+
+	var r *node
+	if n.level == 2 {
+		r = h.baseResult(n)
+	} else {
+		//the 9 overlapping squares of half the size covering n
+		n00, n01, n02 := n.nw, h.join(n.nw.ne, n.ne.nw, n.nw.se, n.ne.sw), n.ne
+		n10 := h.join(n.nw.sw, n.nw.se, n.sw.nw, n.sw.ne)
+		n11 := h.join(n.nw.se, n.ne.sw, n.sw.ne, n.se.nw)
+		n12 := h.join(n.ne.sw, n.ne.se, n.se.nw, n.se.ne)
+		n20, n21, n22 := n.sw, h.join(n.sw.ne, n.se.nw, n.sw.se, n.se.sw), n.se
+
+		//at full speed both halves of the step advance time, otherwise only the second half does
+		firstHalf := h.centre
+		secondStep := step
+		if step == n.level-2 {
+			firstHalf = func(m *node) *node { return h.result(m, step-1) }
+			secondStep = step - 1
+		}
+		r00, r01, r02 := firstHalf(n00), firstHalf(n01), firstHalf(n02)
+		r10, r11, r12 := firstHalf(n10), firstHalf(n11), firstHalf(n12)
+		r20, r21, r22 := firstHalf(n20), firstHalf(n21), firstHalf(n22)
+
+		r = h.join(
+			h.result(h.join(r00, r01, r10, r11), secondStep),
+			h.result(h.join(r01, r02, r11, r12), secondStep),
+			h.result(h.join(r10, r11, r20, r21), secondStep),
+			h.result(h.join(r11, r12, r21, r22), secondStep),
+		)
+	}
+
+	h.results[key] = r
+	return r
+}
+
+// baseResult returns the centre 2x2 cells of a 4x4 node after one turn.
+func (h *hashLifeBackend) baseResult(n *node) *node {
+	var cells [4][4]bool
+	for y, row := range [2][2]*node{{n.nw, n.ne}, {n.sw, n.se}} {
+		for x, child := range row {
+			cells[2*y][2*x] = child.nw.population != 0
+			cells[2*y][2*x+1] = child.ne.population != 0
+			cells[2*y+1][2*x] = child.sw.population != 0
+			cells[2*y+1][2*x+1] = child.se.population != 0
+		}
+	}
+
+	next := func(x, y int) *node {
+		neighbours := 0
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if (dx != 0 || dy != 0) && cells[y+dy][x+dx] {
+					neighbours++
+				}
+			}
+		}
+		if (cells[y][x] && h.rule.survival[neighbours]) || (!cells[y][x] && h.rule.birth[neighbours]) {
+			return h.alive
+		}
+		return h.dead
+	}
+
+	return h.join(next(1, 1), next(2, 1), next(1, 2), next(2, 2))
+}
+
+// advance moves the world forward 2^step turns.
+func (h *hashLifeBackend) advance(step int) {
+	level := h.root.level
+	if step > level {
+		level = step
+	}
+
+	//the result of a tiling at least 4 times the size of the root starts on a whole tile,
+	//so its top-left corner is the new root
+	r := h.result(h.tile(level+2), step)
+	for r.level > h.root.level {
+		r = r.nw
+	}
+	h.setRoot(r)
+
+	if len(h.nodes) > maxHashLifeNodes {
+		h.reset(h.world())
+	}
+}
+
+func (h *hashLifeBackend) sendInitialCellFlips(events chan<- Event, CompletedTurns int) {
+	world := h.world()
+	all_x := Range{start: 0, end: h.dimensions.width}
+	all_y := Range{start: 0, end: h.dimensions.height}
+	all := startFlips(h.flips, events, CompletedTurns)
+	world.partialSendInitialCellFlips(all_x, all_y, all[0])
+	sendFlips(all)
+}
+
+// processTurns advances by the next power of two turns, doubling the step each time it is called
+// so long runs accelerate while short ones still report their first turns individually.
+func (h *hashLifeBackend) processTurns(events chan<- Event, CompletedTurns int, remaining int) (int, error) {
+	for h.step > 0 && 1<<uint(h.step) > remaining {
+		h.step--
+	}
+	turns := 1 << uint(h.step)
+
+	all := startFlips(h.flips, events, CompletedTurns)
+	if all == nil {
+		h.advance(h.step)
+	} else {
+		before := h.world()
+		h.advance(h.step)
+		after := h.world()
+
+		for y := 0; y < h.dimensions.height; y++ {
+			for x := 0; x < h.dimensions.width; x++ {
+				if before.world[y][x] != after.world[y][x] {
+					all[0].add(util.Cell{X: x, Y: y}, after.world[y][x])
+				}
+			}
+		}
+		sendFlips(all)
+	}
+
+	if 2<<uint(h.step) <= remaining-turns {
+		h.step++
+	}
+	return turns, nil
+}
+
+func (h *hashLifeBackend) bareProcessOneTurn() {
+	h.advance(0)
+}
+
+func (h *hashLifeBackend) world() World {
+	world := newWorld(h.dimensions)
+	h.fill(world, h.root, 0, 0)
+	return world
+}
+
+func (h *hashLifeBackend) close() {}
+
+// fill sets the alive cells of n with its top-left cell at x, y, ignoring cells beyond the world.
+func (h *hashLifeBackend) fill(world World, n *node, x, y int) {
+	if n.population == 0 || x >= h.dimensions.width || y >= h.dimensions.height {
+		return
+	}
+	if n.level == 0 {
+		world.world[y][x] = alive
+		return
+	}
+
+	half := 1 << uint(n.level-1)
+	h.fill(world, n.nw, x, y)
+	h.fill(world, n.ne, x+half, y)
+	h.fill(world, n.sw, x, y+half)
+	h.fill(world, n.se, x+half, y+half)
+}
@@ -0,0 +1,160 @@
+package gol
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+const (
+	life105Header = "#Life 1.05"
+	life106Header = "#Life 1.06"
+)
+
+// decodeLife105 returns the Life 1.05 pattern in data read from filename.
+// Rows of . and * follow #P x y lines giving the position of their top left cell, and #N or #R survival/birth give the rule.
+func decodeLife105(filename string, data []byte) (pattern, error) {
+	var cells []util.Cell
+	var rule Rule
+	block_x, block_y, row := 0, 0, 0
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#P"):
+			fields := strings.Fields(line[len("#P"):])
+			x, xErr := strconv.Atoi(fieldOrEmpty(fields, 0))
+			y, yErr := strconv.Atoi(fieldOrEmpty(fields, 1))
+			if len(fields) != 2 || xErr != nil || yErr != nil {
+				return pattern{}, &HeaderError{Filename: filename, Reason: fmt.Sprintf("incorrect block position %q", line)}
+			}
+			block_x, block_y, row = x, y, 0
+			continue
+		case strings.HasPrefix(line, "#N"):
+			rule = Conway
+			continue
+		case strings.HasPrefix(line, "#R"):
+			var err error
+			rule, err = ParseRule(strings.TrimSpace(line[len("#R"):]))
+			if err != nil {
+				return pattern{}, &HeaderError{Filename: filename, Reason: err.Error()}
+			}
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		}
+
+		for x, c := range line {
+			switch c {
+			case '.':
+			case '*', 'O':
+				cells = append(cells, util.Cell{X: block_x + x, Y: block_y + row})
+			default:
+				return pattern{}, &HeaderError{Filename: filename, Reason: fmt.Sprintf("unexpected %q in the pattern", c)}
+			}
+		}
+		row++
+	}
+
+	p := patternOf(cells)
+	p.rule = rule
+	return p, nil
+}
+
+// decodeLife106 returns the Life 1.06 pattern in data read from filename, which lists the x y coordinates of every alive cell.
+func decodeLife106(filename string, data []byte) (pattern, error) {
+	var cells []util.Cell
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		x, xErr := strconv.Atoi(fieldOrEmpty(fields, 0))
+		y, yErr := strconv.Atoi(fieldOrEmpty(fields, 1))
+		if len(fields) != 2 || xErr != nil || yErr != nil {
+			return pattern{}, &HeaderError{Filename: filename, Reason: fmt.Sprintf("incorrect cell %q", line)}
+		}
+		cells = append(cells, util.Cell{X: x, Y: y})
+	}
+	return patternOf(cells), nil
+}
+
+// fieldOrEmpty returns fields[i], or "" if there are not enough fields.
+func fieldOrEmpty(fields []string, i int) string {
+	if i < len(fields) {
+		return fields[i]
+	}
+	return ""
+}
+
+// writeLife105 writes the world to writer as a Life 1.05 pattern in a single block from 0, 0, leaving out trailing dead cells.
+// The rule is only recorded if it can be written in survival/birth notation.
+func (world World) writeLife105(writer io.Writer, rule Rule) error {
+	buffered := bufio.NewWriter(writer)
+	buffered.WriteString(life105Header + "\n")
+	rule = rule.orDefault()
+	switch {
+	case rule.String() == Conway.String():
+		buffered.WriteString("#N\n")
+	case !rule.isLargerThanLife() && rule.states == 2:
+		fmt.Fprintf(buffered, "#R %v/%v\n", countDigits(rule.survival), countDigits(rule.birth))
+	}
+	buffered.WriteString("#P 0 0\n")
+
+	//rows after the last alive cell are left out, and empty rows are written as a single .
+	last := -1
+	for y, row := range world.world {
+		for _, cell := range row {
+			if cell == alive {
+				last = y
+			}
+		}
+	}
+	for _, row := range world.world[:last+1] {
+		end := len(row)
+		for end > 0 && row[end-1] != alive {
+			end--
+		}
+		line := []byte{'.'}
+		if end > 0 {
+			line = make([]byte, end)
+			for x, cell := range row[:end] {
+				if cell == alive {
+					line[x] = '*'
+				} else {
+					line[x] = '.'
+				}
+			}
+		}
+		buffered.Write(line)
+		buffered.WriteByte('\n')
+	}
+	return buffered.Flush()
+}
+
+// countDigits returns the neighbour counts set in counts written as digits, as in the older survival/birth notation.
+func countDigits(counts []bool) string {
+	var builder strings.Builder
+	for n, set := range counts {
+		if set {
+			builder.WriteString(strconv.Itoa(n))
+		}
+	}
+	return builder.String()
+}
+
+// writeLife106 writes the alive cells of the world to writer as a Life 1.06 pattern, one x y pair to a line.
+func (world World) writeLife106(writer io.Writer) error {
+	buffered := bufio.NewWriter(writer)
+	buffered.WriteString(life106Header + "\n")
+	for _, cell := range world.to_cells() {
+		fmt.Fprintf(buffered, "%d %d\n", cell.X, cell.Y)
+	}
+	return buffered.Flush()
+}
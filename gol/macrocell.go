@@ -0,0 +1,224 @@
+package gol
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+const (
+	macrocellHeader = "[M2]"
+	// macrocellLeafLevel is the level of the 8x8 leaves written as rows of . and *
+	macrocellLeafLevel = 3
+)
+
+// macrocellNode is a line of a Macrocell pattern, a square of 2^level cells.
+type macrocellNode struct {
+	level int
+	// cells are the alive cells of a leaf, relative to its top left corner
+	cells []util.Cell
+	// children are the nw, ne, sw and se nodes of anything other than a leaf, as line numbers counting from 1,
+	// with 0 for an empty square, or the states of the cells of a level 1 node in a multi-state pattern
+	children [4]int
+}
+
+// decodeMacrocell returns the Macrocell pattern in data read from filename.
+// The root node is the last line, centred on 0, 0 as in Golly, so its cells are boxed from 0, 0 unless any are negative.
+// Every state other than dead is loaded as alive.
+func decodeMacrocell(filename string, data []byte) (pattern, error) {
+	lines := strings.Split(string(data), "\n")
+	if !strings.HasPrefix(strings.TrimSpace(lines[0]), macrocellHeader) {
+		return pattern{}, &HeaderError{Filename: filename, Reason: "not a macrocell pattern, expected " + macrocellHeader}
+	}
+
+	var rule Rule
+	nodes := []macrocellNode{{}}
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#R"):
+			//Golly follows the rule with a topology
+			notation := strings.TrimSpace(line[len("#R"):])
+			if i := strings.Index(notation, ":"); i >= 0 {
+				notation = notation[:i]
+			}
+			var err error
+			rule, err = ParseRule(notation)
+			if err != nil {
+				return pattern{}, &HeaderError{Filename: filename, Reason: err.Error()}
+			}
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		}
+
+		n, err := parseMacrocellNode(line, len(nodes))
+		if err != nil {
+			return pattern{}, &HeaderError{Filename: filename, Reason: err.Error()}
+		}
+		nodes = append(nodes, n)
+	}
+	if len(nodes) == 1 {
+		return pattern{rule: rule}, nil
+	}
+
+	var cells []util.Cell
+	var collect func(index, x, y int)
+	collect = func(index, x, y int) {
+		n := nodes[index]
+		switch {
+		case index == 0:
+		case n.level == macrocellLeafLevel:
+			for _, cell := range n.cells {
+				cells = append(cells, util.Cell{X: x + cell.X, Y: y + cell.Y})
+			}
+		case n.level == 1:
+			for i, state := range n.children {
+				if state != 0 {
+					cells = append(cells, util.Cell{X: x + i%2, Y: y + i/2})
+				}
+			}
+		default:
+			half := 1 << uint(n.level-1)
+			for i, child := range n.children {
+				collect(child, x+half*(i%2), y+half*(i/2))
+			}
+		}
+	}
+	root := len(nodes) - 1
+	half := 1 << uint(nodes[root].level-1)
+	collect(root, -half, -half)
+
+	p := patternOf(cells)
+	p.rule = rule
+	return p, nil
+}
+
+// parseMacrocellNode parses a line of a Macrocell pattern, which may only refer to the count lines before it.
+func parseMacrocellNode(line string, count int) (macrocellNode, error) {
+	//leaves are rows of . and * each ended by $, with trailing dead cells and rows left out
+	if line[0] == '.' || line[0] == '*' || line[0] == '$' {
+		n := macrocellNode{level: macrocellLeafLevel}
+		x, y := 0, 0
+		for _, c := range line {
+			switch c {
+			case '.':
+				x++
+			case '*':
+				n.cells = append(n.cells, util.Cell{X: x, Y: y})
+				x++
+			case '$':
+				x, y = 0, y+1
+			default:
+				return n, fmt.Errorf("unexpected %q in the leaf %q", c, line)
+			}
+			if x > 8 || y > 8 {
+				return n, fmt.Errorf("leaf %q is larger than 8x8", line)
+			}
+		}
+		return n, nil
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) != 5 {
+		return macrocellNode{}, fmt.Errorf("incorrect node %q", line)
+	}
+	var n macrocellNode
+	var err error
+	if n.level, err = strconv.Atoi(fields[0]); err != nil || n.level < 1 || n.level > 62 {
+		return n, fmt.Errorf("incorrect level in the node %q", line)
+	}
+	for i := range n.children {
+		if n.children[i], err = strconv.Atoi(fields[i+1]); err != nil || n.children[i] < 0 {
+			return n, fmt.Errorf("incorrect child in the node %q", line)
+		}
+		if n.level > 1 && n.children[i] >= count {
+			return n, fmt.Errorf("node %q refers to a line after it", line)
+		}
+	}
+	return n, nil
+}
+
+// macrocellWriter numbers the nodes of a world as they are written, sharing the line of every repeated node.
+type macrocellWriter struct {
+	world  World
+	writer *bufio.Writer
+	lines  map[string]int
+}
+
+// writeMacrocell writes the world to writer as a Macrocell pattern with the world in the south east quadrant of the root,
+// so its cells keep their coordinates when read back, recording the rule.
+// Only alive cells are written, so cells part way through decaying under a Generations rule are written as dead.
+func (world World) writeMacrocell(writer io.Writer, rule Rule) error {
+	m := macrocellWriter{world: world, writer: bufio.NewWriter(writer), lines: make(map[string]int)}
+	fmt.Fprintf(m.writer, "%v\n#R %v\n", macrocellHeader, rule)
+
+	level := macrocellLeafLevel
+	for 1<<uint(level) < world.dimensions.width || 1<<uint(level) < world.dimensions.height {
+		level++
+	}
+	m.line(fmt.Sprintf("%d 0 0 0 %d", level+1, m.node(0, 0, level)))
+	return m.writer.Flush()
+}
+
+// node writes the node of the given level with its top left cell at x, y, after any children not yet written,
+// and returns its line number, or 0 if it is empty.
+func (m *macrocellWriter) node(x, y, level int) int {
+	if x >= m.world.dimensions.width || y >= m.world.dimensions.height {
+		return 0
+	}
+	if level > macrocellLeafLevel {
+		half := 1 << uint(level-1)
+		nw, ne := m.node(x, y, level-1), m.node(x+half, y, level-1)
+		sw, se := m.node(x, y+half, level-1), m.node(x+half, y+half, level-1)
+		if nw == 0 && ne == 0 && sw == 0 && se == 0 {
+			return 0
+		}
+		return m.line(fmt.Sprintf("%d %d %d %d %d", level, nw, ne, sw, se))
+	}
+
+	var leaf strings.Builder
+	rows := 0
+	for row := y; row < y+8; row++ {
+		end := x
+		for i := x; i < x+8 && row < m.world.dimensions.height && i < m.world.dimensions.width; i++ {
+			if m.world.world[row][i] == alive {
+				end = i + 1
+			}
+		}
+		if end == x {
+			rows++
+			continue
+		}
+		leaf.WriteString(strings.Repeat("$", rows))
+		rows = 1
+		for i := x; i < end; i++ {
+			if m.world.world[row][i] == alive {
+				leaf.WriteByte('*')
+			} else {
+				leaf.WriteByte('.')
+			}
+		}
+	}
+	if leaf.Len() == 0 {
+		return 0
+	}
+	leaf.WriteByte('$')
+	return m.line(leaf.String())
+}
+
+// line writes line unless it has already been written, returning its line number.
+func (m *macrocellWriter) line(line string) int {
+	if number, ok := m.lines[line]; ok {
+		return number
+	}
+	m.lines[line] = len(m.lines) + 1
+	m.writer.WriteString(line + "\n")
+	return m.lines[line]
+}
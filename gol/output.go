@@ -0,0 +1,121 @@
+package gol
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultOutputDir is the directory output images are written to if Params.OutputDir is left unset.
+const defaultOutputDir = "out"
+
+// defaultOutputName is the template for the names of output images if Params.OutputName is left unset.
+const defaultOutputName = "{width}x{height}x{turn}"
+
+// image_filename returns the image in images/ of the size given by dimensions.
+func image_filename(dimensions Dimensions) string {
+	return filepath.Join("images", fmt.Sprintf("%vx%v.pgm", dimensions.width, dimensions.height))
+}
+
+// input_filename returns the image to load the world of p from.
+func input_filename(p Params) string {
+	if p.InputPath != "" {
+		return p.InputPath
+	}
+	return image_filename(Dimensions{width: p.ImageWidth, height: p.ImageHeight})
+}
+
+// out_filename returns the file to write the world of p after turns to, for a run which started at started.
+func out_filename(p Params, turns int, started time.Time) string {
+	dir := p.OutputDir
+	if dir == "" {
+		dir = defaultOutputDir
+	}
+	name := p.OutputName
+	if name == "" {
+		name = defaultOutputName
+		if p.Soup {
+			name += "-{soup}"
+		}
+		if p.Topology.Surface != Torus {
+			name += "-{topology}"
+		}
+	}
+
+	//rules contain slashes, which cannot be in a filename
+	name = strings.NewReplacer(
+		"{width}", strconv.Itoa(p.ImageWidth),
+		"{height}", strconv.Itoa(p.ImageHeight),
+		"{turn}", strconv.Itoa(turns),
+		"{rule}", strings.Replace(p.Rule.String(), "/", "_", -1),
+		"{topology}", p.Topology.String(),
+		"{timestamp}", started.Format("20060102-150405"),
+		"{soup}", soup_name(p),
+	).Replace(name)
+	return filepath.Join(dir, name+p.OutputFormat.extension())
+}
+
+// writeOutput writes the world to a file named from p in its output format and returns its filename.
+// Worlds on anything other than a torus record their topology in the pgm or pbm header.
+func writeOutput(world World, turns int, p Params, started time.Time) (string, error) {
+	filename := out_filename(p, turns, started)
+	switch {
+	case p.OutputFormat == RLE:
+		return filename, writeFile(filename, func(writer io.Writer) error {
+			return world.writeRle(writer, p.Rule)
+		})
+	case p.OutputFormat == Cells:
+		return filename, writeFile(filename, func(writer io.Writer) error {
+			return world.writeCells(writer, strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)))
+		})
+	case p.OutputFormat == Life105:
+		return filename, writeFile(filename, func(writer io.Writer) error {
+			return world.writeLife105(writer, p.Rule)
+		})
+	case p.OutputFormat == Life106:
+		return filename, writeFile(filename, world.writeLife106)
+	case p.OutputFormat == Macrocell:
+		return filename, writeFile(filename, func(writer io.Writer) error {
+			return world.writeMacrocell(writer, p.Rule)
+		})
+	case p.OutputFormat == PNG:
+		return filename, writeFile(filename, world.writePng)
+	}
+
+	var comments []string
+	if p.Topology.Surface != Torus {
+		comments = append(comments, "topology "+p.Topology.String())
+	}
+	if p.OutputFormat == PBM {
+		return filename, writeFile(filename, world.netpbm("P4", comments...).Write)
+	}
+	return filename, world.writePgmImage(filename, comments...)
+}
+
+// InputParams returns p with the image width and height read from its input image if either is left unset,
+// and the rule recorded in the input image if the rule is left unset.
+// When attaching to a broker, an unset width or height is taken from the simulation running on it instead.
+// The input is kept in the params returned, so a run started with them does not read it again.
+func InputParams(p Params) (Params, error) {
+	if p.Attach && (p.ImageWidth == 0 || p.ImageHeight == 0) {
+		return attachedParams(p)
+	}
+	if p.Resume != "" || p.Attach {
+		return p, nil
+	}
+	world, rule, err := readInput(p)
+	if err != nil {
+		return p, err
+	}
+	p.ImageWidth, p.ImageHeight = world.dimensions.width, world.dimensions.height
+	if p.Rule.birth == nil {
+		p.Rule = rule
+	}
+	if !p.Soup {
+		p.input = &input{key: inputKeyOf(p), world: world, rule: rule}
+	}
+	return p, nil
+}
@@ -0,0 +1,70 @@
+package gol
+
+import (
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// pattern is the alive cells of a pattern read from a file, in a box of width by height with its top left corner at 0, 0.
+type pattern struct {
+	cells  []util.Cell
+	width  int
+	height int
+	// rule is the rule recorded with the pattern, or the zero Rule if its format does not record one
+	rule Rule
+}
+
+// patternOf returns the pattern of the alive cells, boxed from 0, 0 to the furthest cell.
+// Cells given relative to a centre may be negative, in which case the box starts from the leftmost and topmost cells instead.
+func patternOf(cells []util.Cell) pattern {
+	p := pattern{cells: cells}
+	if len(cells) == 0 {
+		return p
+	}
+
+	left, top := 0, 0
+	for _, cell := range cells {
+		if cell.X < left {
+			left = cell.X
+		}
+		if cell.Y < top {
+			top = cell.Y
+		}
+	}
+	for i, cell := range cells {
+		cells[i] = util.Cell{X: cell.X - left, Y: cell.Y - top}
+		if cells[i].X >= p.width {
+			p.width = cells[i].X + 1
+		}
+		if cells[i].Y >= p.height {
+			p.height = cells[i].Y + 1
+		}
+	}
+	return p
+}
+
+// place returns the world on a board the size of dimensions with the pattern read from filename
+// placed with its top left corner at offset_x, offset_y.
+// A width or height of 0 in dimensions is taken from the size of the pattern and its offset instead.
+func (p pattern) place(filename string, dimensions Dimensions, offset_x, offset_y int) (World, error) {
+	if dimensions.width == 0 {
+		dimensions.width = offset_x + p.width
+	}
+	if dimensions.height == 0 {
+		dimensions.height = offset_y + p.height
+	}
+	if offset_x < 0 || offset_y < 0 || offset_x+p.width > dimensions.width || offset_y+p.height > dimensions.height {
+		return World{}, &DimensionError{
+			Filename:    filename,
+			Width:       offset_x + p.width,
+			Height:      offset_y + p.height,
+			ImageWidth:  dimensions.width,
+			ImageHeight: dimensions.height,
+		}
+	}
+
+	world := newWorld(dimensions)
+	for _, cell := range p.cells {
+		world.world[offset_y+cell.Y][offset_x+cell.X] = alive
+	}
+	return world, nil
+}
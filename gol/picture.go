@@ -0,0 +1,170 @@
+package gol
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+)
+
+// Dither selects how the grey levels of a picture are converted to alive and dead cells.
+type Dither int
+
+const (
+	// Threshold makes every cell at least half way to white alive.
+	Threshold Dither = iota
+	// FloydSteinberg carries the difference between each cell and its grey level on to the cells right of and below it,
+	// so areas of grey are drawn with a proportion of alive cells.
+	FloydSteinberg
+	// Ordered compares each cell with a tiled 8x8 Bayer matrix of thresholds instead of carrying differences on.
+	Ordered
+)
+
+var ditherNames = map[Dither]string{
+	Threshold:      "threshold",
+	FloydSteinberg: "floyd-steinberg",
+	Ordered:        "ordered",
+}
+
+// bayer is the 8x8 Bayer matrix, giving the order cells of a tile are made alive in as the grey level increases.
+var bayer = [8][8]int{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+// ParseDither parses the name of a dither as returned by Dither.String.
+func ParseDither(name string) (Dither, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for dither, ditherName := range ditherNames {
+		if name == ditherName {
+			return dither, nil
+		}
+	}
+	return Threshold, fmt.Errorf("dither %q: expected one of threshold, floyd-steinberg or ordered", name)
+}
+
+func (dither Dither) String() string {
+	return ditherNames[dither]
+}
+
+// Set parses name into the dither, allowing a Dither to be used as a flag.Value.
+func (dither *Dither) Set(name string) error {
+	parsed, err := ParseDither(name)
+	if err != nil {
+		return err
+	}
+	*dither = parsed
+	return nil
+}
+
+// isPicture returns whether data is a picture in one of the formats registered with the image package: PNG, JPEG or GIF.
+func isPicture(data []byte) bool {
+	_, _, err := image.DecodeConfig(bytes.NewReader(data))
+	return err == nil
+}
+
+// decodePicture returns the world on a board the size of dimensions with the picture in data read from filename
+// scaled to fit it, lighter parts of the picture becoming alive cells as chosen by dither.
+// A width or height of 0 in dimensions is taken from the picture, keeping its aspect ratio if the other is given.
+// Only the first frame of an animated GIF is loaded.
+func decodePicture(filename string, data []byte, dimensions Dimensions, dither Dither) (World, error) {
+	picture, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return World{}, &HeaderError{Filename: filename, Reason: err.Error()}
+	}
+	bounds := picture.Bounds()
+	source_width, source_height := bounds.Dx(), bounds.Dy()
+	if source_width == 0 || source_height == 0 {
+		return World{}, &HeaderError{Filename: filename, Reason: "empty picture"}
+	}
+
+	switch {
+	case dimensions.width == 0 && dimensions.height == 0:
+		dimensions = Dimensions{width: source_width, height: source_height}
+	case dimensions.width == 0:
+		dimensions.width = (dimensions.height*source_width + source_height/2) / source_height
+	case dimensions.height == 0:
+		dimensions.height = (dimensions.width*source_height + source_width/2) / source_width
+	}
+	if dimensions.width <= 0 || dimensions.height <= 0 {
+		return World{}, &HeaderError{Filename: filename, Reason: fmt.Sprintf("cannot scale a %vx%v picture to %vx%v", source_width, source_height, dimensions.width, dimensions.height)}
+	}
+
+	levels := scaleGray(picture, dimensions)
+	world := newWorld(dimensions)
+	for y, row := range levels {
+		for x, level := range row {
+			var isAlive bool
+			switch dither {
+			case FloydSteinberg:
+				isAlive = level >= 128
+				//the difference is carried right, then down left, down and down right
+				difference := level
+				if isAlive {
+					difference -= 255
+				}
+				carry := func(x, y int, weight float64) {
+					if x >= 0 && x < dimensions.width && y < dimensions.height {
+						levels[y][x] += difference * weight / 16
+					}
+				}
+				carry(x+1, y, 7)
+				carry(x-1, y+1, 3)
+				carry(x, y+1, 5)
+				carry(x+1, y+1, 1)
+			case Ordered:
+				isAlive = level > (float64(bayer[y%8][x%8])+0.5)*255/64
+			default:
+				isAlive = level >= 128
+			}
+			if isAlive {
+				world.world[y][x] = alive
+			}
+		}
+	}
+	return world, nil
+}
+
+// scaleGray returns the grey levels of picture scaled to dimensions, from 0 for black to 255 for white.
+// Each cell is the average of the pixels it covers when shrinking, or the pixel it falls in when enlarging.
+func scaleGray(picture image.Image, dimensions Dimensions) [][]float64 {
+	bounds := picture.Bounds()
+	source_width, source_height := bounds.Dx(), bounds.Dy()
+
+	//the range of source pixels covered by cell i of n along an edge of length size
+	covered := func(i, n, size int) Range {
+		r := Range{start: i * size / n, end: (i + 1) * size / n}
+		if r.end == r.start {
+			r.end = r.start + 1
+		}
+		return r
+	}
+
+	levels := make([][]float64, dimensions.height)
+	for y := range levels {
+		levels[y] = make([]float64, dimensions.width)
+		range_y := covered(y, dimensions.height, source_height)
+		for x := range levels[y] {
+			range_x := covered(x, dimensions.width, source_width)
+			total := 0.0
+			for source_y := range_y.start; source_y < range_y.end; source_y++ {
+				for source_x := range_x.start; source_x < range_x.end; source_x++ {
+					gray := color.Gray16Model.Convert(picture.At(bounds.Min.X+source_x, bounds.Min.Y+source_y)).(color.Gray16)
+					total += float64(gray.Y) / 257
+				}
+			}
+			levels[y][x] = total / float64((range_y.end-range_y.start)*(range_x.end-range_x.start))
+		}
+	}
+	return levels
+}
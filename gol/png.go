@@ -0,0 +1,21 @@
+package gol
+
+import (
+	"image"
+	"image/png"
+	"io"
+)
+
+// gray returns a copy of the world as a greyscale image with a pixel for every cell.
+func (world World) gray() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, world.dimensions.width, world.dimensions.height))
+	for y, row := range world.world {
+		copy(img.Pix[y*img.Stride:], row)
+	}
+	return img
+}
+
+// writePng writes the world to writer as a greyscale PNG image, with the same grey levels as a pgm image.
+func (world World) writePng(writer io.Writer) error {
+	return png.Encode(writer, world.gray())
+}
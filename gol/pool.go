@@ -0,0 +1,103 @@
+package gol
+
+import "sync"
+
+// barrier blocks goroutines until a fixed number of them are waiting, then releases them all.
+// It can be reused as soon as they have been released.
+type barrier struct {
+	mutex      sync.Mutex
+	cond       *sync.Cond
+	parties    int
+	waiting    int
+	generation int
+}
+
+func newBarrier(parties int) *barrier {
+	b := &barrier{parties: parties}
+	b.cond = sync.NewCond(&b.mutex)
+	return b
+}
+
+func (b *barrier) wait() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	generation := b.generation
+	b.waiting++
+	if b.waiting == b.parties {
+		b.waiting = 0
+		b.generation++
+		b.cond.Broadcast()
+		return
+	}
+
+	for generation == b.generation {
+		b.cond.Wait()
+	}
+}
+
+// block is a rectangle of the world processed by a single worker.
+type block struct {
+	x Range
+	y Range
+}
+
+// pool is a set of long-lived workers, each owning a fixed set of blocks of the world.
+// Workers wait at a barrier for the next task, run it on their blocks, then wait at the barrier again
+// until every block is done, so no goroutines are started after the pool is created.
+type pool struct {
+	// blocks[i] are the blocks of worker i, a contiguous run of the blocks the pool was created with
+	blocks  [][]block
+	barrier *barrier
+	// task is set by run before releasing the workers, and is nil when they should exit
+	task func(worker int, range_x, range_y Range)
+}
+
+func newPool(threads int, blocks []block) *pool {
+	p := &pool{barrier: newBarrier(threads + 1)}
+	for i := 0; i < threads; i++ {
+		assigned := get_sliced_range(i, threads, len(blocks))
+		p.blocks = append(p.blocks, blocks[assigned.start:assigned.end])
+	}
+
+	for i, blocks := range p.blocks {
+		go p.work(i, blocks)
+	}
+
+	return p
+}
+
+func (p *pool) work(worker int, blocks []block) {
+	for {
+		//wait for a task
+		p.barrier.wait()
+		task := p.task
+		if task == nil {
+			return
+		}
+		for _, b := range blocks {
+			task(worker, b.x, b.y)
+		}
+		//wait for every other worker to finish
+		p.barrier.wait()
+	}
+}
+
+// run calls task on every block in parallel along with the index of the worker it belongs to,
+// returning when all of them have finished.
+func (p *pool) run(task func(worker int, range_x, range_y Range)) {
+	p.task = task
+	p.barrier.wait()
+	p.barrier.wait()
+}
+
+// threads returns the number of workers in the pool.
+func (p *pool) threads() int {
+	return len(p.blocks)
+}
+
+// close stops the workers, the pool cannot be used afterwards.
+func (p *pool) close() {
+	p.task = nil
+	p.barrier.wait()
+}
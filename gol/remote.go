@@ -0,0 +1,169 @@
+package gol
+
+import (
+	"fmt"
+	"net/rpc"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// remoteBackend runs the world on a broker, keeping a copy of it up to date from the turns the broker completes.
+type remoteBackend struct {
+	client  *rpc.Client
+	id      int
+	rule    Rule
+	current World
+	flips   []*flips
+	// pending are turns collected from the broker but not yet processed
+	pending []TurnUpdate
+	// shutdown is true once the broker has been shut down, detached once the simulation has been left running on it
+	shutdown bool
+	detached bool
+}
+
+func newRemoteBackend(p Params, world World, rule Rule, CompletedTurns int, batch bool) (*remoteBackend, error) {
+	client, err := rpc.Dial("tcp", p.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	req := StartRequest{
+		Turns:          p.Turns,
+		CompletedTurns: CompletedTurns,
+		Rule:           rule.String(),
+		Topology:       p.Topology.String(),
+		Width:          world.dimensions.width,
+		Height:         world.dimensions.height,
+		World:          world.world,
+	}
+	var res StartResponse
+	err = client.Call("Broker.Start", req, &res)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &remoteBackend{client: client, id: res.ID, rule: rule, current: world, flips: newFlips(1, rule, batch)}, nil
+}
+
+// attachRemoteBackend connects to the simulation left running on a broker,
+// returning it along with p updated to the turns, rule and topology of the simulation and the turns it has completed.
+// The width and height of the simulation are used if p leaves them unset, and must match p otherwise.
+func attachRemoteBackend(p Params) (*remoteBackend, Params, int, error) {
+	client, err := rpc.Dial("tcp", p.Server)
+	if err != nil {
+		return nil, p, 0, err
+	}
+
+	var res AttachResponse
+	err = client.Call("Broker.Attach", AttachRequest{}, &res)
+	//a width or height left unset is taken from the simulation
+	if err == nil && p.ImageWidth == 0 {
+		p.ImageWidth = res.Width
+	}
+	if err == nil && p.ImageHeight == 0 {
+		p.ImageHeight = res.Height
+	}
+	if err == nil && (res.Width != p.ImageWidth || res.Height != p.ImageHeight) {
+		_ = client.Call("Broker.Detach", DetachRequest{ID: res.ID}, &DetachResponse{})
+		err = fmt.Errorf("the simulation on the broker is %vx%v, not %vx%v", res.Width, res.Height, p.ImageWidth, p.ImageHeight)
+	}
+	if err == nil {
+		p.Rule, err = ParseRule(res.Rule)
+	}
+	if err == nil {
+		p.Topology, err = ParseTopology(res.Topology)
+	}
+	if err != nil {
+		client.Close()
+		return nil, p, 0, err
+	}
+	p.Turns = res.Turns
+
+	world := World{world: res.World, dimensions: Dimensions{width: res.Width, height: res.Height}}
+	r := &remoteBackend{client: client, id: res.ID, rule: p.Rule, current: world, flips: newFlips(1, p.Rule, p.BatchFlips)}
+	return r, p, res.CompletedTurns, nil
+}
+
+// attachedParams returns p with the width and height of the simulation on the broker,
+// attaching to it only to detach again straight away.
+func attachedParams(p Params) (Params, error) {
+	r, simParams, _, err := attachRemoteBackend(p)
+	if err != nil {
+		return p, err
+	}
+	err = r.detach()
+	r.client.Close()
+	p.ImageWidth, p.ImageHeight = simParams.ImageWidth, simParams.ImageHeight
+	return p, err
+}
+
+// sendInitialCellFlips sends events for every cell that is not dead in the world as of the last turn collected,
+// resynchronising a controller attaching part way through a simulation.
+func (r *remoteBackend) sendInitialCellFlips(events chan<- Event, CompletedTurns int) {
+	all_x := Range{start: 0, end: r.current.dimensions.width}
+	all_y := Range{start: 0, end: r.current.dimensions.height}
+	all := startFlips(r.flips, events, CompletedTurns)
+	r.current.partialSendInitialCellFlips(all_x, all_y, all[0])
+	sendFlips(all)
+}
+
+// detach leaves the simulation running on the broker without this controller.
+func (r *remoteBackend) detach() error {
+	err := r.client.Call("Broker.Detach", DetachRequest{ID: r.id}, &DetachResponse{})
+	r.detached = err == nil
+	return err
+}
+
+// processTurns applies the next turn completed by the broker, collecting more from it if none are pending.
+func (r *remoteBackend) processTurns(events chan<- Event, CompletedTurns int, remaining int) (int, error) {
+	for len(r.pending) == 0 {
+		var res UpdatesResponse
+		if err := r.client.Call("Broker.Updates", UpdatesRequest{ID: r.id, Max: remaining}, &res); err != nil {
+			return 0, err
+		}
+		r.pending = res.Updates
+	}
+
+	update := r.pending[0]
+	r.pending = r.pending[1:]
+	if events != nil {
+		for _, failure := range update.Failures {
+			events <- WorkerFailed{CompletedTurns: CompletedTurns, Worker: failure.Address, Reason: failure.Reason, Workers: failure.Workers}
+		}
+	}
+	all := startFlips(r.flips, events, CompletedTurns)
+	for i, cell := range update.Cells {
+		r.current.world[cell.Y][cell.X] = update.Values[i]
+		if all != nil {
+			all[0].add(cell, update.Values[i])
+		}
+	}
+	sendFlips(all)
+	return 1, nil
+}
+
+func (r *remoteBackend) bareProcessOneTurn() {
+	_, err := r.processTurns(nil, 0, 0)
+	util.Check(err)
+}
+
+func (r *remoteBackend) world() World {
+	return r.current
+}
+
+// close stops the simulation on the broker unless it has finished, been detached from or the broker has been shut down.
+func (r *remoteBackend) close() {
+	if !r.shutdown && !r.detached {
+		//the simulation is no longer running if it finished and another has been started since
+		_ = r.client.Call("Broker.Stop", StopRequest{ID: r.id}, &StopResponse{})
+	}
+	r.client.Close()
+}
+
+// shutdownServers shuts down the broker and its workers.
+func (r *remoteBackend) shutdownServers() error {
+	err := r.client.Call("Broker.Shutdown", ShutdownRequest{}, &ShutdownResponse{})
+	r.shutdown = err == nil
+	return err
+}
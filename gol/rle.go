@@ -0,0 +1,167 @@
+package gol
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// rleLineLength is the longest line written in the body of an RLE pattern.
+const rleLineLength = 70
+
+// decodeRle returns the RLE pattern in data read from filename, in a box the size given in its header.
+func decodeRle(filename string, data []byte) (pattern, error) {
+	var p pattern
+	var body []string
+	width, height := -1, -1
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if width >= 0 {
+			body = append(body, line)
+			continue
+		}
+
+		//x = m, y = n, rule = B3/S23, where the rule may itself contain commas and is followed by a topology in Golly
+		header := line
+		if i := strings.Index(header, "rule"); i >= 0 {
+			notation := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(header[i+len("rule"):]), "="))
+			if j := strings.Index(notation, ":"); j >= 0 {
+				notation = notation[:j]
+			}
+			var err error
+			p.rule, err = ParseRule(notation)
+			if err != nil {
+				return p, &HeaderError{Filename: filename, Reason: err.Error()}
+			}
+			header = header[:i]
+		}
+		for _, field := range strings.Split(header, ",") {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			value, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+			if err != nil {
+				return p, &HeaderError{Filename: filename, Reason: fmt.Sprintf("incorrect %v", strings.TrimSpace(field))}
+			}
+			switch strings.TrimSpace(parts[0]) {
+			case "x":
+				width = value
+			case "y":
+				height = value
+			}
+		}
+		if width < 0 || height < 0 {
+			return p, &HeaderError{Filename: filename, Reason: "not an rle pattern, expected an x = m, y = n header"}
+		}
+	}
+	if width < 0 {
+		return p, &HeaderError{Filename: filename, Reason: "not an rle pattern, expected an x = m, y = n header"}
+	}
+	p.width, p.height = width, height
+
+	x, y, count := 0, 0, 0
+	for _, c := range strings.Join(body, "") {
+		switch {
+		case c == '!':
+			return p, nil
+		case c >= '0' && c <= '9':
+			count = count*10 + int(c-'0')
+			continue
+		case unicode.IsSpace(c):
+			continue
+		}
+
+		run := count
+		if run == 0 {
+			run = 1
+		}
+		count = 0
+		switch {
+		case c == '$':
+			x, y = 0, y+run
+		case c == 'b' || c == '.':
+			x += run
+		case unicode.IsLetter(c):
+			//every state other than dead is written with a letter, all of which are loaded as alive
+			if x+run > width || y >= height {
+				return p, &HeaderError{Filename: filename, Reason: "cells beyond the x and y of the header"}
+			}
+			for i := 0; i < run; i++ {
+				p.cells = append(p.cells, util.Cell{X: x + i, Y: y})
+			}
+			x += run
+		default:
+			return p, &HeaderError{Filename: filename, Reason: fmt.Sprintf("unexpected %q in the pattern", c)}
+		}
+	}
+	return p, nil
+}
+
+// writeRle writes the world to writer as an RLE pattern the size of the world, recording rule in the header.
+// Only alive cells are written, so cells part way through decaying under a Generations rule are written as dead.
+func (world World) writeRle(writer io.Writer, rule Rule) error {
+	_, ioError := fmt.Fprintf(writer, "x = %d, y = %d, rule = %v\n", world.dimensions.width, world.dimensions.height, rule)
+	if ioError != nil {
+		return ioError
+	}
+
+	var lines []string
+	line := ""
+	emit := func(run int, tag byte) {
+		if run == 0 {
+			return
+		}
+		token := string(tag)
+		if run > 1 {
+			token = strconv.Itoa(run) + token
+		}
+		if len(line)+len(token) > rleLineLength {
+			lines = append(lines, line)
+			line = ""
+		}
+		line += token
+	}
+
+	//rows are ended by $, with trailing dead cells and rows left out
+	newlines := 0
+	for y, row := range world.world {
+		if y > 0 {
+			newlines++
+		}
+		end := len(row)
+		for end > 0 && row[end-1] != alive {
+			end--
+		}
+		if end == 0 {
+			continue
+		}
+		emit(newlines, '$')
+		newlines = 0
+
+		for x := 0; x < end; {
+			run := 1
+			for x+run < end && (row[x+run] == alive) == (row[x] == alive) {
+				run++
+			}
+			if row[x] == alive {
+				emit(run, 'o')
+			} else {
+				emit(run, 'b')
+			}
+			x += run
+		}
+	}
+	emit(1, '!')
+	lines = append(lines, line)
+
+	_, ioError = io.WriteString(writer, strings.Join(lines, "\n")+"\n")
+	return ioError
+}
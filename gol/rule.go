@@ -0,0 +1,367 @@
+package gol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	dead  byte = 0
+	alive byte = 255
+)
+
+// Rule describes a Life-like cellular automaton using the standard B/S notation,
+// e.g. "B3/S23" for Conway's Game of Life or "B36/S23" for HighLife.
+// Generations rules add a third part giving the number of states, e.g. "B2/S/C3" for Brian's Brain,
+// and Larger-than-Life rules use Golly's notation, e.g. "R5,C0,M1,S34..58,B34..45,NM" for Bosco's Rule.
+// The zero Rule behaves as Conway's Game of Life.
+type Rule struct {
+	// birth[n] is true if a dead cell with n alive neighbours becomes alive
+	birth []bool
+	// survival[n] is true if an alive cell with n alive neighbours stays alive
+	survival []bool
+	// states is the number of cell states including dead and alive, more than 2 for Generations rules
+	states int
+	// radius is the distance from a cell to the edge of its neighbourhood
+	radius int
+	// neighbourhood is the shape of the neighbourhood
+	neighbourhood Neighbourhood
+	// middle is true if a cell counts itself as one of its neighbours
+	middle bool
+}
+
+// Neighbourhood is the shape of the cells counted as neighbours by a Rule.
+type Neighbourhood int
+
+const (
+	// Moore neighbourhoods are the square of cells within radius in both directions.
+	Moore Neighbourhood = iota
+	// VonNeumann neighbourhoods are the diamond of cells within radius in Manhattan distance.
+	VonNeumann
+)
+
+// maxRadius is the largest radius supported by Larger-than-Life rules.
+const maxRadius = 10
+
+// Conway is the rule used when Params.Rule is left unset.
+var Conway = Rule{
+	birth:    []bool{false, false, false, true, false, false, false, false, false},
+	survival: []bool{false, false, true, true, false, false, false, false, false},
+	states:   2,
+	radius:   1,
+}
+
+// ParseRule parses a rule in B/S notation ("B36/S23"), S/B notation ("S23/B36")
+// or the older survival/birth notation ("23/36").
+// Generations rules are written with a trailing state count, as in "B2/S/C3" or "/2/3".
+// Larger-than-Life rules are written as "Rr,Cc,Mm,Smin..max,Bmin..max,Nn".
+func ParseRule(notation string) (Rule, error) {
+	if strings.Contains(notation, ",") {
+		return parseLargerThanLife(notation)
+	}
+
+	rule := Rule{birth: make([]bool, 9), survival: make([]bool, 9), states: 2, radius: 1}
+
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(notation)), "/")
+	if len(parts) != 2 && len(parts) != 3 {
+		return Rule{}, fmt.Errorf("rule %q: expected two or three parts separated by '/'", notation)
+	}
+
+	//the older notation has no letters and lists survival, birth then states
+	if !strings.ContainsAny(strings.Join(parts, ""), "BSC") {
+		parts[0] = "S" + parts[0]
+		parts[1] = "B" + parts[1]
+		if len(parts) == 3 {
+			parts[2] = "C" + parts[2]
+		}
+	}
+
+	seen := make(map[byte]bool)
+	for _, part := range parts {
+		if part == "" || (part[0] != 'B' && part[0] != 'S' && part[0] != 'C') || seen[part[0]] {
+			return Rule{}, fmt.Errorf("rule %q: expected one B part, one S part and an optional C part", notation)
+		}
+		seen[part[0]] = true
+
+		if part[0] == 'C' {
+			states, err := strconv.Atoi(part[1:])
+			if err != nil || states < 2 || states > 256 {
+				return Rule{}, fmt.Errorf("rule %q: number of states must be between 2 and 256", notation)
+			}
+			rule.states = states
+			continue
+		}
+
+		counts := rule.birth
+		if part[0] == 'S' {
+			counts = rule.survival
+		}
+		for _, digit := range part[1:] {
+			if digit < '0' || digit > '8' {
+				return Rule{}, fmt.Errorf("rule %q: invalid neighbour count %q", notation, digit)
+			}
+			counts[digit-'0'] = true
+		}
+	}
+	if !seen['B'] || !seen['S'] {
+		return Rule{}, fmt.Errorf("rule %q: expected one B part, one S part and an optional C part", notation)
+	}
+
+	return rule, nil
+}
+
+// parseLargerThanLife parses a rule in Golly's Larger-than-Life notation, e.g. "R5,C0,M1,S34..58,B34..45,NM".
+// The radius and both ranges are required, the states default to 2, the middle to excluded and the neighbourhood to Moore.
+func parseLargerThanLife(notation string) (Rule, error) {
+	rule := Rule{states: 2, neighbourhood: Moore}
+	var birth, survival *Range
+
+	seen := make(map[byte]bool)
+	for _, part := range strings.Split(strings.ToUpper(strings.TrimSpace(notation)), ",") {
+		if len(part) < 2 || seen[part[0]] {
+			return Rule{}, fmt.Errorf("rule %q: invalid or repeated part %q", notation, part)
+		}
+		seen[part[0]] = true
+
+		var err error
+		switch part[0] {
+		case 'R':
+			rule.radius, err = strconv.Atoi(part[1:])
+			if err == nil && (rule.radius < 1 || rule.radius > maxRadius) {
+				err = fmt.Errorf("radius must be between 1 and %d", maxRadius)
+			}
+		case 'C':
+			rule.states, err = strconv.Atoi(part[1:])
+			if rule.states == 0 {
+				rule.states = 2
+			}
+			if err == nil && (rule.states < 2 || rule.states > 256) {
+				err = fmt.Errorf("number of states must be between 2 and 256")
+			}
+		case 'M':
+			switch part[1:] {
+			case "0":
+				rule.middle = false
+			case "1":
+				rule.middle = true
+			default:
+				err = fmt.Errorf("middle must be 0 or 1")
+			}
+		case 'N':
+			switch part[1:] {
+			case "M":
+				rule.neighbourhood = Moore
+			case "N":
+				rule.neighbourhood = VonNeumann
+			default:
+				err = fmt.Errorf("neighbourhood must be M or N")
+			}
+		case 'B':
+			birth, err = parseCountRange(part[1:])
+		case 'S':
+			survival, err = parseCountRange(part[1:])
+		default:
+			err = fmt.Errorf("unknown part %q", part)
+		}
+		if err != nil {
+			return Rule{}, fmt.Errorf("rule %q: %v", notation, err)
+		}
+	}
+	if rule.radius == 0 || birth == nil || survival == nil {
+		return Rule{}, fmt.Errorf("rule %q: expected R, B and S parts", notation)
+	}
+
+	var err error
+	if rule.birth, err = rangeCounts(*birth, rule.maxNeighbours()); err != nil {
+		return Rule{}, fmt.Errorf("rule %q: %v", notation, err)
+	}
+	if rule.survival, err = rangeCounts(*survival, rule.maxNeighbours()); err != nil {
+		return Rule{}, fmt.Errorf("rule %q: %v", notation, err)
+	}
+
+	return rule, nil
+}
+
+// rangeCounts returns a slice indexed by neighbour count which is true for the counts within r.
+func rangeCounts(r Range, maxNeighbours int) ([]bool, error) {
+	if r.end > maxNeighbours {
+		return nil, fmt.Errorf("range %d..%d exceeds the %d cells in the neighbourhood", r.start, r.end, maxNeighbours)
+	}
+
+	counts := make([]bool, maxNeighbours+1)
+	for n := r.start; n <= r.end; n++ {
+		counts[n] = true
+	}
+	return counts, nil
+}
+
+// parseCountRange parses an inclusive range of neighbour counts written as "min..max".
+func parseCountRange(notation string) (*Range, error) {
+	bounds := strings.Split(notation, "..")
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("range %q must be written as min..max", notation)
+	}
+	start, startErr := strconv.Atoi(bounds[0])
+	end, endErr := strconv.Atoi(bounds[1])
+	if startErr != nil || endErr != nil || start < 0 || end < start {
+		return nil, fmt.Errorf("range %q must be written as min..max", notation)
+	}
+	return &Range{start, end}, nil
+}
+
+// String returns the rule in canonical B/S notation, with a C part for Generations rules,
+// or in Larger-than-Life notation if the rule has a larger or different neighbourhood.
+func (rule Rule) String() string {
+	rule = rule.orDefault()
+	if rule.isLargerThanLife() {
+		return rule.largerThanLifeString()
+	}
+
+	var builder strings.Builder
+	builder.WriteString("B")
+	for n, born := range rule.birth {
+		if born {
+			fmt.Fprint(&builder, n)
+		}
+	}
+	builder.WriteString("/S")
+	for n, survives := range rule.survival {
+		if survives {
+			fmt.Fprint(&builder, n)
+		}
+	}
+	if rule.states > 2 {
+		fmt.Fprintf(&builder, "/C%d", rule.states)
+	}
+
+	return builder.String()
+}
+
+// largerThanLifeString returns the rule in Larger-than-Life notation.
+// Birth and survival are written as the range between the smallest and largest counts.
+func (rule Rule) largerThanLifeString() string {
+	countRange := func(counts []bool) string {
+		r := Range{start: -1, end: -1}
+		for n, set := range counts {
+			if set {
+				if r.start < 0 {
+					r.start = n
+				}
+				r.end = n
+			}
+		}
+		return fmt.Sprintf("%d..%d", r.start, r.end)
+	}
+
+	middle := 0
+	if rule.middle {
+		middle = 1
+	}
+	neighbourhood := "M"
+	if rule.neighbourhood == VonNeumann {
+		neighbourhood = "N"
+	}
+	states := rule.states
+	if states == 2 {
+		states = 0
+	}
+
+	return fmt.Sprintf("R%d,C%d,M%d,S%s,B%s,N%s",
+		rule.radius, states, middle, countRange(rule.survival), countRange(rule.birth), neighbourhood)
+}
+
+// Set parses notation into the rule, allowing a Rule to be used as a flag.Value.
+func (rule *Rule) Set(notation string) error {
+	parsed, err := ParseRule(notation)
+	if err != nil {
+		return err
+	}
+	*rule = parsed
+	return nil
+}
+
+// orDefault returns Conway if the rule is the zero Rule.
+func (rule Rule) orDefault() Rule {
+	if rule.birth == nil {
+		return Conway
+	}
+	return rule
+}
+
+// isLargerThanLife returns true if the rule needs more than the 8 cells of the radius 1 Moore neighbourhood.
+func (rule Rule) isLargerThanLife() bool {
+	return rule.radius > 1 || rule.neighbourhood != Moore || rule.middle
+}
+
+// maxNeighbours returns the number of cells counted in the neighbourhood of a cell.
+func (rule Rule) maxNeighbours() int {
+	var cells int
+	if rule.neighbourhood == VonNeumann {
+		cells = 2*rule.radius*(rule.radius+1) + 1
+	} else {
+		cells = (2*rule.radius + 1) * (2*rule.radius + 1)
+	}
+	if !rule.middle {
+		cells--
+	}
+	return cells
+}
+
+// isGenerations returns true if cells pass through decay states when they die.
+func (rule Rule) isGenerations() bool {
+	return rule.states > 2
+}
+
+// value returns the byte stored in the world for a state.
+// State 0 is dead (0), state 1 is alive (255) and the decay states 2..states-1 fade towards black.
+func (rule Rule) value(state int) byte {
+	switch state {
+	case 0:
+		return dead
+	case 1:
+		return alive
+	default:
+		return byte(255 * (rule.states - state) / (rule.states - 1))
+	}
+}
+
+// state returns the state of a byte from the world, rounding unknown grey levels to a nearby state.
+func (rule Rule) state(value byte) int {
+	switch {
+	case value == dead:
+		return 0
+	case value == alive:
+		return 1
+	case !rule.isGenerations():
+		if value >= 128 {
+			return 1
+		}
+		return 0
+	}
+
+	state := rule.states - (int(value)*(rule.states-1)+254)/255
+	if state < 2 {
+		return 2
+	}
+	return state
+}
+
+// next returns the new value of a cell given its current value and number of alive neighbours.
+func (rule Rule) next(cell byte, neighbours int) byte {
+	switch cell {
+	case dead:
+		if rule.birth[neighbours] {
+			return alive
+		}
+		return dead
+	case alive:
+		if rule.survival[neighbours] {
+			return alive
+		}
+		return rule.value(2 % rule.states)
+	default:
+		//decaying cells ignore their neighbours and step towards dead
+		return rule.value((rule.state(cell) + 1) % rule.states)
+	}
+}
@@ -0,0 +1,195 @@
+package gol
+
+import (
+	"io"
+	"io/ioutil"
+	"runtime"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// Engine is a Game of Life world which can be edited, evolved and inspected directly,
+// for embedding the simulator without the events and key presses used by Run, which is built on it.
+// An Engine is not safe for concurrent use, and should be closed once finished with.
+type Engine struct {
+	p       Params
+	backend backend
+	turn    int
+	// current is the world as of the last turn, collected from the backend the first time it is needed after a turn
+	current *World
+	// edited is true once Set has changed current, so the backend has to be started again from it before the next turn
+	edited bool
+}
+
+// New returns an engine for a world of width by height dead cells, evolved by Conway's Game of Life
+// on a torus using the byte engine with a thread for each CPU, until changed by Configure.
+func New(width, height int) *Engine {
+	return newEngine(newWorld(Dimensions{width: width, height: height}))
+}
+
+// Load returns an engine for the world in the image, picture or pattern read from r, detecting its format by its content.
+// The engine is configured as by New, but with the rule recorded in the pattern if it records one.
+func Load(r io.Reader) (*Engine, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	world, rule, err := decodeImage("", data, Dimensions{}, 0, 0, Threshold)
+	if err != nil {
+		return nil, err
+	}
+	e := newEngine(world)
+	if rule.birth != nil {
+		p := e.p
+		p.Rule = rule
+		if err := e.Configure(p); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+func newEngine(world World) *Engine {
+	e := &Engine{p: Params{
+		ImageWidth:  world.dimensions.width,
+		ImageHeight: world.dimensions.height,
+		Threads:     runtime.NumCPU(),
+	}}
+	//the byte engine supports every world
+	util.Check(e.start(world))
+	return e
+}
+
+// start replaces the backend with one evolving world from the current turn.
+func (e *Engine) start(world World) error {
+	world.normalise(e.p.Rule.orDefault())
+	backend, err := newBackend(e.p, world, e.turn)
+	if err != nil {
+		return err
+	}
+	if e.backend != nil {
+		e.backend.close()
+	}
+	e.backend, e.current, e.edited = backend, nil, false
+	return nil
+}
+
+// Configure sets the rule, topology, engine and tiling used for the turns after the current one from p,
+// along with the threads unless p leaves them unset. The image size, turns and everything else in p are ignored.
+// If the engine does not support the rest of p, Configure returns an error and leaves the engine unchanged.
+func (e *Engine) Configure(p Params) error {
+	configured := e.p
+	e.p.Rule, e.p.Topology, e.p.Engine = p.Rule, p.Topology, p.Engine
+	e.p.ActiveTiles, e.p.Tiles, e.p.TileSize = p.ActiveTiles, p.Tiles, p.TileSize
+	if p.Threads > 0 {
+		e.p.Threads = p.Threads
+	}
+
+	//start from a copy, as the world may be shared with the backend being replaced
+	world := e.world()
+	copied := newWorld(world.dimensions)
+	for y := range world.world {
+		copy(copied.world[y], world.world[y])
+	}
+	err := e.start(copied)
+	if err != nil {
+		e.p = configured
+	}
+	return err
+}
+
+// world returns the world as of the current turn.
+func (e *Engine) world() World {
+	if e.current == nil {
+		world := e.backend.world()
+		e.current = &world
+	}
+	return *e.current
+}
+
+// Set makes the cell at x, y alive or dead.
+func (e *Engine) Set(x, y int, live bool) {
+	world := e.world()
+	if live {
+		world.world[y][x] = alive
+	} else {
+		world.world[y][x] = dead
+	}
+	e.edited = true
+}
+
+// Get returns true if the cell at x, y is alive.
+func (e *Engine) Get(x, y int) bool {
+	return e.world().world[y][x] == alive
+}
+
+// Step evolves the world by n turns.
+func (e *Engine) Step(n int) {
+	for n > 0 {
+		//only a broker can fail to complete a turn, and Configure never runs the world on one
+		turns, err := e.processTurns(nil, n)
+		util.Check(err)
+		n -= turns
+	}
+}
+
+// processTurns evolves the world by at least one and at most remaining turns, sending events for every cell
+// that changed unless events is nil, and returns the number of turns completed.
+func (e *Engine) processTurns(events chan<- Event, remaining int) (int, error) {
+	if e.edited {
+		if err := e.start(e.world()); err != nil {
+			return 0, err
+		}
+	}
+	turns, err := e.backend.processTurns(events, e.turn, remaining)
+	if err != nil {
+		return 0, err
+	}
+	e.turn += turns
+	e.current = nil
+	return turns, nil
+}
+
+// sendInitialCellFlips sends events for every cell that is not dead as of the current turn.
+func (e *Engine) sendInitialCellFlips(events chan<- Event) {
+	if e.edited {
+		util.Check(e.start(e.world()))
+	}
+	e.backend.sendInitialCellFlips(events, e.turn)
+}
+
+// Alive returns the cells that are alive.
+func (e *Engine) Alive() []util.Cell {
+	return e.world().to_cells()
+}
+
+// Population returns the number of cells that are alive.
+func (e *Engine) Population() int {
+	population := 0
+	for _, row := range e.world().world {
+		for _, cell := range row {
+			if cell == alive {
+				population++
+			}
+		}
+	}
+	return population
+}
+
+// Turn returns the number of turns completed.
+func (e *Engine) Turn() int {
+	return e.turn
+}
+
+// Save writes the world to w as a binary pgm image, recording the topology in the header unless it is a torus.
+func (e *Engine) Save(w io.Writer) error {
+	if e.p.Topology.Surface == Torus {
+		return e.world().writePgm(w)
+	}
+	return e.world().writePgm(w, "topology "+e.p.Topology.String())
+}
+
+// Close stops any workers started by the engine, which cannot be used afterwards.
+func (e *Engine) Close() {
+	e.backend.close()
+}
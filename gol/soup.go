@@ -0,0 +1,161 @@
+package gol
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// defaultDensity is the proportion of alive cells in a soup if Params.Density is left unset.
+const defaultDensity = 0.5
+
+// Symmetry is the symmetry group a soup is made to have, named as by apgsearch.
+type Symmetry int
+
+const (
+	// C1 is no symmetry.
+	C1 Symmetry = iota
+	// C2 is symmetry under a half turn about the centre.
+	C2
+	// C4 is symmetry under quarter turns about the centre, for square soups.
+	C4
+	// D2 is symmetry under reflection in the vertical line through the centre.
+	D2
+	// D4 is symmetry under reflection in the vertical and horizontal lines through the centre.
+	D4
+	// D8 is symmetry under quarter turns and reflection in both lines and both diagonals, for square soups.
+	D8
+)
+
+var symmetryNames = map[Symmetry]string{
+	C1: "C1",
+	C2: "C2",
+	C4: "C4",
+	D2: "D2",
+	D4: "D4",
+	D8: "D8",
+}
+
+// ParseSymmetry parses the name of a symmetry as returned by Symmetry.String.
+func ParseSymmetry(name string) (Symmetry, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	for symmetry, symmetryName := range symmetryNames {
+		if name == symmetryName {
+			return symmetry, nil
+		}
+	}
+	return C1, fmt.Errorf("symmetry %q: expected one of C1, C2, C4, D2, D4 or D8", name)
+}
+
+func (symmetry Symmetry) String() string {
+	return symmetryNames[symmetry]
+}
+
+// Set parses name into the symmetry, allowing a Symmetry to be used as a flag.Value.
+func (symmetry *Symmetry) Set(name string) error {
+	parsed, err := ParseSymmetry(name)
+	if err != nil {
+		return err
+	}
+	*symmetry = parsed
+	return nil
+}
+
+// orbit returns the indices of the cells of a width by height soup which x, y is mapped to by the symmetry, including itself.
+func (symmetry Symmetry) orbit(x, y, width, height int) []int {
+	index := func(x, y int) int {
+		return y*width + x
+	}
+	identity := index(x, y)
+	halfTurn := index(width-1-x, height-1-y)
+	mirror := index(width-1-x, y)
+	flip := index(x, height-1-y)
+
+	switch symmetry {
+	case C2:
+		return []int{identity, halfTurn}
+	case C4:
+		return []int{identity, index(width-1-y, x), halfTurn, index(y, width-1-x)}
+	case D2:
+		return []int{identity, mirror}
+	case D4:
+		return []int{identity, mirror, flip, halfTurn}
+	case D8:
+		return []int{identity, index(width-1-y, x), halfTurn, index(y, width-1-x),
+			mirror, flip, index(y, x), index(width-1-y, height-1-x)}
+	default:
+		return []int{identity}
+	}
+}
+
+// soupSize returns the width and height of the soup of p, the whole board if p.SoupWidth or p.SoupHeight is left unset.
+func soupSize(p Params) (int, int) {
+	width, height := p.SoupWidth, p.SoupHeight
+	if width == 0 {
+		width = p.ImageWidth
+	}
+	if height == 0 {
+		height = p.ImageHeight
+	}
+	return width, height
+}
+
+// soup_name returns the seed, density and symmetry of the soup of p to record in the names of output images,
+// followed by its size if it only covers part of the board.
+func soup_name(p Params) string {
+	density := p.Density
+	if density == 0 {
+		density = defaultDensity
+	}
+	name := fmt.Sprintf("soup%v-%v-%v", p.Seed, strconv.FormatFloat(density, 'f', -1, 64), p.Symmetry)
+	if width, height := soupSize(p); width != p.ImageWidth || height != p.ImageHeight {
+		name += fmt.Sprintf("-%vx%v", width, height)
+	}
+	return name
+}
+
+// newSoup returns a board the size of p filled at random from p.Seed, with the proportion p.Density of alive cells
+// in a rectangle of p.SoupWidth by p.SoupHeight at its centre, made to have p.Symmetry.
+// Every cell is drawn in turn whatever the symmetry, and copies the cell drawn for the first cell of its orbit,
+// so the same params always give the same soup.
+func newSoup(p Params) (World, error) {
+	width, height := soupSize(p)
+	density := p.Density
+	if density == 0 {
+		density = defaultDensity
+	}
+	switch {
+	case p.ImageWidth <= 0 || p.ImageHeight <= 0:
+		return World{}, fmt.Errorf("soup needs a width and height for the board, got %vx%v", p.ImageWidth, p.ImageHeight)
+	case width <= 0 || height <= 0 || width > p.ImageWidth || height > p.ImageHeight:
+		return World{}, fmt.Errorf("soup of %vx%v does not fit on a board of %vx%v", width, height, p.ImageWidth, p.ImageHeight)
+	case density < 0 || density > 1:
+		return World{}, fmt.Errorf("soup density %v must be between 0 and 1", density)
+	case (p.Symmetry == C4 || p.Symmetry == D8) && width != height:
+		return World{}, fmt.Errorf("soup of %vx%v must be square for %v symmetry", width, height, p.Symmetry)
+	}
+
+	random := rand.New(rand.NewSource(p.Seed))
+	drawn := make([]bool, width*height)
+	for i := range drawn {
+		drawn[i] = random.Float64() < density
+	}
+
+	world := newWorld(Dimensions{width: p.ImageWidth, height: p.ImageHeight})
+	left, top := (p.ImageWidth-width)/2, (p.ImageHeight-height)/2
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			first := y*width + x
+			for _, i := range p.Symmetry.orbit(x, y, width, height) {
+				if i < first {
+					first = i
+				}
+			}
+			if drawn[first] {
+				world.world[top+y][left+x] = alive
+			}
+		}
+	}
+	return world, nil
+}
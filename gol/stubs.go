@@ -0,0 +1,115 @@
+package gol
+
+import "uk.ac.bris.cs/gameoflife/util"
+
+// The requests and responses sent over net/rpc between controllers, the broker and its workers.
+// Rules and topologies are sent in their string form.
+
+// TurnUpdate is the cells changed by one turn of a simulation on the broker.
+type TurnUpdate struct {
+	// CompletedTurns is the number of turns completed before this one
+	CompletedTurns int
+	Cells          []util.Cell
+	// Values[i] is the new value of Cells[i]
+	Values []byte
+	// Failures are the workers lost while processing the turn
+	Failures []WorkerFailure
+}
+
+// WorkerFailure is a worker lost by the broker.
+type WorkerFailure struct {
+	Address string
+	Reason  string
+	// Workers is the number of workers remaining
+	Workers int
+}
+
+type RegisterRequest struct {
+	Address string
+}
+
+type RegisterResponse struct {
+	Workers int
+}
+
+type StartRequest struct {
+	Turns int
+	// CompletedTurns is the number of turns already completed when resuming a run
+	CompletedTurns int
+	Rule           string
+	Topology       string
+	Width          int
+	Height         int
+	World          [][]byte
+}
+
+type StartResponse struct {
+	// ID identifies the simulation in later requests
+	ID      int
+	Workers int
+}
+
+type UpdatesRequest struct {
+	ID int
+	// Max is the largest number of turns to return, every buffered turn if 0
+	Max int
+}
+
+type UpdatesResponse struct {
+	Updates []TurnUpdate
+}
+
+type StopRequest struct {
+	ID int
+}
+
+type StopResponse struct {
+	CompletedTurns int
+}
+
+type DetachRequest struct {
+	ID int
+}
+
+type DetachResponse struct {
+	CompletedTurns int
+}
+
+type AttachRequest struct{}
+
+type AttachResponse struct {
+	ID             int
+	Turns          int
+	CompletedTurns int
+	Rule           string
+	Topology       string
+	Width          int
+	Height         int
+	World          [][]byte
+}
+
+type ShutdownRequest struct{}
+
+type ShutdownResponse struct {
+	CompletedTurns int
+}
+
+type StripRequest struct {
+	Rule     string
+	Topology string
+	Width    int
+	Height   int
+	// Rows are the rows of the world read when processing the strip, and Numbers the row each of them is in the world
+	Rows    [][]byte
+	Numbers []int
+	// Start and End are the rows of the strip to process, End being exclusive
+	Start int
+	End   int
+}
+
+type StripResponse struct {
+	// Rows are the rows of the strip after the turn
+	Rows   [][]byte
+	Cells  []util.Cell
+	Values []byte
+}
@@ -0,0 +1,162 @@
+package gol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Surface is the shape the world is wrapped onto.
+type Surface int
+
+const (
+	// Torus joins the top edge to the bottom and the left edge to the right.
+	Torus Surface = iota
+	// Plane is bounded by dead cells beyond every edge.
+	Plane
+	// Reflective edges mirror the cells just inside them.
+	Reflective
+	// KleinBottle joins the left edge to the right and the top edge to the bottom with a horizontal flip.
+	KleinBottle
+	// CrossSurface joins both pairs of opposite edges with a flip, making a projective plane.
+	CrossSurface
+	// TwistedTorus joins the edges like a torus but shifts cells horizontally when crossing the top or bottom edge.
+	TwistedTorus
+)
+
+var surfaceNames = map[Surface]string{
+	Torus:        "torus",
+	Plane:        "plane",
+	Reflective:   "reflective",
+	KleinBottle:  "klein",
+	CrossSurface: "cross",
+	TwistedTorus: "twisted",
+}
+
+// Topology describes how cells beyond the edges of the world are found.
+// The zero Topology is a torus.
+type Topology struct {
+	Surface Surface
+	// Shift is the number of cells moved right when crossing the bottom edge of a TwistedTorus
+	Shift int
+}
+
+// ParseTopology parses one of "torus", "plane", "reflective", "klein", "cross"
+// or "twisted+N" for a twisted torus with a shift of N.
+func ParseTopology(name string) (Topology, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	if strings.HasPrefix(name, surfaceNames[TwistedTorus]) {
+		shift, err := strconv.Atoi(strings.TrimPrefix(name, surfaceNames[TwistedTorus]))
+		if err != nil {
+			return Topology{}, fmt.Errorf("topology %q: expected a shift, e.g. twisted+1", name)
+		}
+		return Topology{Surface: TwistedTorus, Shift: shift}, nil
+	}
+
+	for surface, surfaceName := range surfaceNames {
+		if name == surfaceName {
+			return Topology{Surface: surface}, nil
+		}
+	}
+
+	return Topology{}, fmt.Errorf("topology %q: expected one of torus, plane, reflective, klein, cross or twisted+N", name)
+}
+
+// String returns the name of the topology as accepted by ParseTopology.
+func (topology Topology) String() string {
+	if topology.Surface == TwistedTorus {
+		return fmt.Sprintf("%v%+d", surfaceNames[TwistedTorus], topology.Shift)
+	}
+	return surfaceNames[topology.Surface]
+}
+
+// Set parses name into the topology, allowing a Topology to be used as a flag.Value.
+func (topology *Topology) Set(name string) error {
+	parsed, err := ParseTopology(name)
+	if err != nil {
+		return err
+	}
+	*topology = parsed
+	return nil
+}
+
+// locate returns the cell in the world found at x, y, which may be beyond the edges of the world.
+// ok is false if there is no such cell and the position should be treated as dead.
+func (topology Topology) locate(x, y int, dimensions Dimensions) (int, int, bool) {
+	width, height := dimensions.width, dimensions.height
+
+	switch topology.Surface {
+	case Plane:
+		return x, y, x >= 0 && x < width && y >= 0 && y < height
+	case Reflective:
+		return reflect(x, width), reflect(y, height), true
+	case KleinBottle:
+		if crossings(y, height)%2 != 0 {
+			x = width - 1 - x
+		}
+		return wrap(x, width), wrap(y, height), true
+	case CrossSurface:
+		flipX := crossings(y, height)%2 != 0
+		flipY := crossings(x, width)%2 != 0
+		if flipX {
+			x = width - 1 - x
+		}
+		if flipY {
+			y = height - 1 - y
+		}
+		return wrap(x, width), wrap(y, height), true
+	case TwistedTorus:
+		x += crossings(y, height) * topology.Shift
+		return wrap(x, width), wrap(y, height), true
+	default:
+		return wrap(x, width), wrap(y, height), true
+	}
+}
+
+// rowsRead returns the rows of the world read when counting the neighbours within radius of the cells in rows,
+// in ascending order. Rows beyond the top and bottom edges are wrapped or reflected back into the world,
+// and the left and right edges of a CrossSurface flip cells to the mirrored rows.
+func (topology Topology) rowsRead(rows Range, radius int, dimensions Dimensions) []int {
+	read := make([]bool, dimensions.height)
+	for y := rows.start - radius; y < rows.end+radius; y++ {
+		//the row a cell is found in only depends on how many times it crossed the left or right edge,
+		//so the columns within the world are all found in the same row as column 0
+		for x := -radius; x <= 0; x++ {
+			if _, ry, ok := topology.locate(x, y, dimensions); ok {
+				read[ry] = true
+			}
+		}
+		for x := dimensions.width; x < dimensions.width+radius; x++ {
+			if _, ry, ok := topology.locate(x, y, dimensions); ok {
+				read[ry] = true
+			}
+		}
+	}
+
+	var numbers []int
+	for y, ok := range read {
+		if ok {
+			numbers = append(numbers, y)
+		}
+	}
+	return numbers
+}
+
+// crossings returns how many times an edge is crossed to get from the world to v,
+// negative when crossing the top or left edges.
+func crossings(v int, limit int) int {
+	if v < 0 {
+		return (v+1)/limit - 1
+	}
+	return v / limit
+}
+
+// reflect mirrors v back into the world, so -1 is reflected to 0 and limit to limit-1.
+func reflect(v int, limit int) int {
+	v = wrap(v, 2*limit)
+	if v >= limit {
+		return 2*limit - 1 - v
+	}
+	return v
+}
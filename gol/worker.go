@@ -0,0 +1,94 @@
+package gol
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"sync"
+
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// worker processes strips of the world sent by the broker.
+// It keeps no state between turns, so any worker can process any strip.
+type worker struct {
+	listener net.Listener
+	done     chan struct{}
+	// shutdown closes done and listener only once, if the broker asks the worker to shut down again
+	shutdown sync.Once
+}
+
+// ServeWorker registers a worker listening on listener with the broker at the given address,
+// then processes strips sent by the broker until it is shut down.
+func ServeWorker(listener net.Listener, broker string) error {
+	w := &worker{listener: listener, done: make(chan struct{})}
+	server := rpc.NewServer()
+	err := server.RegisterName("Worker", w)
+	if err != nil {
+		return err
+	}
+
+	client, err := rpc.Dial("tcp", broker)
+	if err != nil {
+		return err
+	}
+	err = client.Call("Broker.Register", RegisterRequest{Address: listener.Addr().String()}, &RegisterResponse{})
+	client.Close()
+	if err != nil {
+		return err
+	}
+
+	return serve(server, listener, w.done)
+}
+
+// ProcessStrip works out the next turn of the rows of a strip.
+func (w *worker) ProcessStrip(req StripRequest, res *StripResponse) error {
+	rule, err := ParseRule(req.Rule)
+	if err != nil {
+		return err
+	}
+	topology, err := ParseTopology(req.Topology)
+	if err != nil {
+		return err
+	}
+
+	//the rows sent are put back in their place in the world, leaving the rows which are never read nil
+	world := World{world: make([][]byte, req.Height), dimensions: Dimensions{width: req.Width, height: req.Height}}
+	for j, y := range req.Numbers {
+		if y < 0 || y >= req.Height || len(req.Rows[j]) != req.Width {
+			return fmt.Errorf("row %v: expected a row of %v cells within %v rows", y, req.Width, req.Height)
+		}
+		world.world[y] = req.Rows[j]
+	}
+	//only the rows of the strip are written
+	newWorld := World{world: make([][]byte, req.Height), dimensions: world.dimensions}
+	for y := req.Start; y < req.End; y++ {
+		newWorld.world[y] = make([]byte, req.Width)
+	}
+
+	range_x := Range{start: 0, end: req.Width}
+	range_y := Range{start: req.Start, end: req.End}
+	world.barePartialProcessOneTurn(newWorld, range_x, range_y, world.newStripCounter(rule, topology, range_y), nil)
+
+	for y := req.Start; y < req.End; y++ {
+		for x := 0; x < req.Width; x++ {
+			if newWorld.world[y][x] != world.world[y][x] {
+				res.Cells = append(res.Cells, util.Cell{X: x, Y: y})
+				res.Values = append(res.Values, newWorld.world[y][x])
+			}
+		}
+	}
+	res.Rows = newWorld.world[req.Start:req.End]
+
+	return nil
+}
+
+// Shutdown stops the worker once it has replied.
+func (w *worker) Shutdown(req ShutdownRequest, res *ShutdownResponse) error {
+	var err error
+	w.shutdown.Do(func() {
+		close(w.done)
+		err = w.listener.Close()
+	})
+	return err
+}
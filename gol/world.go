@@ -1,11 +1,8 @@
 package gol
 
 import (
-	"io/ioutil"
-	"os"
-	"strconv"
-	"strings"
-	"sync"
+	"bytes"
+	"io"
 
 	"uk.ac.bris.cs/gameoflife/util"
 )
@@ -15,6 +12,12 @@ type Dimensions struct {
 	height int
 }
 
+// the bounds of the tile sizes chosen by auto_tile_size
+const (
+	minTileSize = 4
+	maxTileSize = 64
+)
+
 // start and end are inclusive
 type Range struct {
 	start int
@@ -35,75 +38,91 @@ func newWorld(dimensions Dimensions) World {
 	return World{world, dimensions}
 }
 
-func (world World) processOneTurnWithThreads(newWorld World, threads int, events chan<- Event, CompletedTurns int) {
-	var wg sync.WaitGroup
-
-	for i := 0; i < threads; i++ {
-		range_x := Range{start: 0, end: world.dimensions.width}
-		range_y := get_sliced_range(i, threads, world.dimensions.height)
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			world.partialProcessOneTurn(newWorld, range_x, range_y, events, CompletedTurns)
-		}()
+// processOneTurnWithThreads writes the next turn into newWorld, recording the cells changed by each worker in its flips unless all is nil.
+func (world World) processOneTurnWithThreads(newWorld World, workers *pool, rule Rule, topology Topology, tracker *activity, all []*flips) {
+	counter := world.newCounter(rule, topology)
+	if tracker != nil {
+		tracker.prepare()
 	}
 
-	wg.Wait()
+	workers.run(func(worker int, range_x, range_y Range) {
+		world.partialProcessOneTurn(newWorld, range_x, range_y, counter, tracker, workerFlips(all, worker))
+	})
 }
 
-func (world World) partialProcessOneTurn(newWorld World, range_x, range_y Range, events chan<- Event, CompletedTurns int) {
+// partialProcessOneTurn updates the cells in range_x and range_y.
+// If tracker is not nil, the columns of its tiles starting in range_x are updated instead,
+// copying the tiles which cannot have changed.
+func (world World) partialProcessOneTurn(newWorld World, range_x, range_y Range, counter counter, tracker *activity, f *flips) {
 	for y := range_y.start; y < range_y.end; y++ {
-		for x := range_x.start; x < range_x.end; x++ {
-			world.update_cell(newWorld, x, y, events, CompletedTurns)
+		if tracker == nil {
+			for x := range_x.start; x < range_x.end; x++ {
+				world.update_cell(newWorld, x, y, counter, f)
+			}
+			continue
 		}
-	}
-}
 
-func (world World) sendInitialCellFlips(threads int, events chan<- Event) {
-	var wg sync.WaitGroup
-
-	for i := 0; i < threads; i++ {
-		range_x := Range{start: 0, end: world.dimensions.width}
-		range_y := get_sliced_range(i, threads, world.dimensions.height)
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			world.partialSendInitialCellFlips(range_x, range_y, events)
-		}()
+		for column := tracker.column(range_x.start); column < tracker.column(range_x.end); column++ {
+			tile_x := tracker.columnRange(column)
+			changed := false
+			if tracker.isActive(column, y) {
+				for x := tile_x.start; x < tile_x.end; x++ {
+					if world.update_cell(newWorld, x, y, counter, f) {
+						changed = true
+					}
+				}
+			} else {
+				copy(newWorld.world[y][tile_x.start:tile_x.end], world.world[y][tile_x.start:tile_x.end])
+			}
+			tracker.changed[y][column] = changed
+		}
 	}
+}
 
-	wg.Wait()
+func (world World) sendInitialCellFlips(workers *pool, all []*flips) {
+	workers.run(func(worker int, range_x, range_y Range) {
+		world.partialSendInitialCellFlips(range_x, range_y, all[worker])
+	})
 }
 
-func (world World) partialSendInitialCellFlips(range_x, range_y Range, events chan<- Event) {
+func (world World) partialSendInitialCellFlips(range_x, range_y Range, f *flips) {
 	for y := range_y.start; y < range_y.end; y++ {
 		for x := range_x.start; x < range_x.end; x++ {
 			if world.world[y][x] != 0 {
-				events <- CellFlipped{CompletedTurns: 0, Cell: util.Cell{X: x, Y: y}}
+				f.add(util.Cell{X: x, Y: y}, world.world[y][x])
 			}
 		}
 	}
 }
 
-func (world World) update_cell(newWorld World, x int, y int, events chan<- Event, CompletedTurns int) {
-	neighbors := world.countNeigbours(x, y)
-	if world.world[y][x] != 0 {
-		if neighbors < 2 || neighbors > 3 {
-			newWorld.world[y][x] = 0
-			//send flip event
-			events <- CellFlipped{CompletedTurns: CompletedTurns, Cell: util.Cell{X: x, Y: y}}
-		} else {
-			newWorld.world[y][x] = world.world[y][x]
+// update_cell writes the next value of a cell to newWorld, returning true if it changed.
+func (world World) update_cell(newWorld World, x int, y int, counter counter, f *flips) bool {
+	neighbors := counter.count(x, y)
+	newWorld.world[y][x] = counter.rule.next(world.world[y][x], neighbors)
+	if newWorld.world[y][x] != world.world[y][x] {
+		//record flip
+		if f != nil {
+			f.add(util.Cell{X: x, Y: y}, newWorld.world[y][x])
 		}
-	} else {
-		if neighbors == 3 {
-			newWorld.world[y][x] = 255
-			//send flip event
-			events <- CellFlipped{CompletedTurns: CompletedTurns, Cell: util.Cell{X: x, Y: y}}
-		} else {
-			newWorld.world[y][x] = 0
+		return true
+	}
+	return false
+}
+
+// cellChangedEvent returns the event for a cell changing to value.
+// Cells under a Generations rule do not simply toggle, so they report their new value instead of flipping.
+func cellChangedEvent(rule Rule, CompletedTurns int, cell util.Cell, value byte) Event {
+	if rule.isGenerations() {
+		return CellStateChanged{CompletedTurns: CompletedTurns, Cell: cell, Value: value}
+	}
+	return CellFlipped{CompletedTurns: CompletedTurns, Cell: cell}
+}
+
+// normalise rounds every cell to a value representing one of the states of rule.
+func (world World) normalise(rule Rule) {
+	for y := 0; y < world.dimensions.height; y++ {
+		for x := 0; x < world.dimensions.width; x++ {
+			world.world[y][x] = rule.value(rule.state(world.world[y][x]))
 		}
 	}
 }
@@ -112,7 +131,7 @@ func (world World) to_cells() []util.Cell {
 	cells := make([]util.Cell, 0)
 	for y := 0; y < world.dimensions.height; y++ {
 		for x := 0; x < world.dimensions.width; x++ {
-			if world.world[y][x] != 0 {
+			if world.world[y][x] == alive {
 				cells = append(cells, util.Cell{X: x, Y: y})
 			}
 		}
@@ -128,28 +147,28 @@ func (world World) countNeigbours(x int, y int) int {
 	wrapX := func(v int) int { return wrap(v, world.dimensions.width) }
 
 	//reads from top-left to bottom-right
-	if world.world[wrapY(y+1)][wrapX(x-1)] != 0 {
+	if world.world[wrapY(y+1)][wrapX(x-1)] == alive {
 		neighbors++
 	}
-	if world.world[wrapY(y+1)][x] != 0 {
+	if world.world[wrapY(y+1)][x] == alive {
 		neighbors++
 	}
-	if world.world[wrapY(y+1)][wrapX(x+1)] != 0 {
+	if world.world[wrapY(y+1)][wrapX(x+1)] == alive {
 		neighbors++
 	}
-	if world.world[y][wrapX(x-1)] != 0 {
+	if world.world[y][wrapX(x-1)] == alive {
 		neighbors++
 	}
-	if world.world[y][wrapX(x+1)] != 0 {
+	if world.world[y][wrapX(x+1)] == alive {
 		neighbors++
 	}
-	if world.world[wrapY(y-1)][wrapX(x-1)] != 0 {
+	if world.world[wrapY(y-1)][wrapX(x-1)] == alive {
 		neighbors++
 	}
-	if world.world[wrapY(y-1)][x] != 0 {
+	if world.world[wrapY(y-1)][x] == alive {
 		neighbors++
 	}
-	if world.world[wrapY(y-1)][wrapX(x+1)] != 0 {
+	if world.world[wrapY(y-1)][wrapX(x+1)] == alive {
 		neighbors++
 	}
 
@@ -158,9 +177,9 @@ func (world World) countNeigbours(x int, y int) int {
 
 func wrap(v int, limit int) int {
 	if v < 0 {
-		return limit - 1
+		return (v%limit + limit) % limit
 	} else if v >= limit {
-		return 0
+		return v % limit
 	} else {
 		return v
 	}
@@ -179,109 +198,133 @@ func get_sliced_range(position, threads, length int) Range {
 	return Range{start, end}
 }
 
-// writePgmImage receives an array of bytes and writes it to a pgm file.
-func (world World) writePgmImage(filename string) {
-	_ = os.Mkdir("out", os.ModePerm)
-
-	file, ioError := os.Create(filename)
-	util.Check(ioError)
-	defer file.Close()
-
-	_, _ = file.WriteString("P5\n")
-	_, _ = file.WriteString(strconv.Itoa(world.dimensions.width))
-	_, _ = file.WriteString(" ")
-	_, _ = file.WriteString(strconv.Itoa(world.dimensions.height))
-	_, _ = file.WriteString("\n")
-	_, _ = file.WriteString(strconv.Itoa(255))
-	_, _ = file.WriteString("\n")
-
-	for y := 0; y < world.dimensions.height; y++ {
-		for x := 0; x < world.dimensions.width; x++ {
-			_, ioError = file.Write([]byte{world.world[y][x]})
-			util.Check(ioError)
+// get_strips splits the world into a strip of rows for each thread.
+func get_strips(threads int, dimensions Dimensions) []block {
+	strips := make([]block, threads)
+	for i := range strips {
+		strips[i] = block{
+			x: Range{start: 0, end: dimensions.width},
+			y: get_sliced_range(i, threads, dimensions.height),
 		}
 	}
-
-	ioError = file.Sync()
-	util.Check(ioError)
+	return strips
 }
 
-// readPgmImage opens a pgm file returns that file as a 2d byte array
-func readPgmImage(expected_dimensions Dimensions) World {
-
-	data, ioError := ioutil.ReadFile("images/" + strconv.Itoa(expected_dimensions.width) + "x" + strconv.Itoa(expected_dimensions.height) + ".pgm")
-	util.Check(ioError)
-
-	fields := strings.Fields(string(data))
-
-	if fields[0] != "P5" {
-		panic("Not a pgm file")
-	}
-
-	width, _ := strconv.Atoi(fields[1])
-	if width != expected_dimensions.width {
-		panic("Incorrect width")
+// get_tiles splits the world into square tiles of size cells, row by row,
+// with smaller tiles along the right and bottom edges if size does not divide the world.
+func get_tiles(size int, dimensions Dimensions) []block {
+	var tiles []block
+	for y := 0; y < dimensions.height; y += size {
+		for x := 0; x < dimensions.width; x += size {
+			tile := block{
+				x: Range{start: x, end: x + size},
+				y: Range{start: y, end: y + size},
+			}
+			if tile.x.end > dimensions.width {
+				tile.x.end = dimensions.width
+			}
+			if tile.y.end > dimensions.height {
+				tile.y.end = dimensions.height
+			}
+			tiles = append(tiles, tile)
+		}
 	}
+	return tiles
+}
 
-	height, _ := strconv.Atoi(fields[2])
-	if height != expected_dimensions.height {
-		panic("Incorrect height")
+// auto_tile_size picks the largest tile size up to maxTileSize giving at least 2 tiles per thread,
+// keeping each tile small enough to stay in cache while balancing the load between threads.
+func auto_tile_size(threads int, dimensions Dimensions) int {
+	size := maxTileSize
+	tiles := func(size int) int {
+		return ((dimensions.width + size - 1) / size) * ((dimensions.height + size - 1) / size)
 	}
-
-	maxval, _ := strconv.Atoi(fields[3])
-	if maxval != 255 {
-		panic("Incorrect maxval/bit depth")
+	for size > minTileSize && tiles(size) < 2*threads {
+		size /= 2
 	}
+	return size
+}
 
-	image := []byte(fields[4])
+// writePgmImage receives an array of bytes and writes it to a pgm file.
+// Each comment is written to the header on its own line.
+func (world World) writePgmImage(filename string, comments ...string) error {
+	return writeFile(filename, func(writer io.Writer) error {
+		return world.writePgm(writer, comments...)
+	})
+}
 
-	world := newWorld(expected_dimensions)
+// writePgm writes the world to writer in pgm format, with each comment on its own line of the header.
+func (world World) writePgm(writer io.Writer, comments ...string) error {
+	return world.netpbm("P5", comments...).Write(writer)
+}
 
-	for i, cell := range image {
-		world.world[i/height][i%width] = cell
+// netpbm returns the world as an image in the Netpbm format of magic, sharing its rows.
+func (world World) netpbm(magic string, comments ...string) util.Netpbm {
+	return util.Netpbm{
+		Magic:    magic,
+		Width:    world.dimensions.width,
+		Height:   world.dimensions.height,
+		Comments: comments,
+		Pixels:   world.world,
 	}
-
-	return world
 }
 
-func (world World) bareProcessOneTurn(newWorld World, threads int) {
-	var wg sync.WaitGroup
+// decodeNetpbm returns the world in the P1, P2, P4 or P5 image in data read from filename, taking its size from the header.
+func decodeNetpbm(filename string, data []byte) (World, error) {
+	image, err := util.ReadNetpbm(bytes.NewReader(data))
+	if pixels, ok := err.(*util.PixelError); ok {
+		return World{}, &PixelError{Filename: filename, Reason: pixels.Error()}
+	} else if err != nil {
+		return World{}, &HeaderError{Filename: filename, Reason: err.Error()}
+	}
+	return worldOf(image), nil
+}
 
-	for i := 0; i < threads; i++ {
-		range_x := Range{start: 0, end: world.dimensions.width}
-		range_y := get_sliced_range(i, threads, world.dimensions.height)
+// worldOf returns the world with the pixels of image as its cells.
+func worldOf(image util.Netpbm) World {
+	return World{world: image.Pixels, dimensions: Dimensions{width: image.Width, height: image.Height}}
+}
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			world.barePartialProcessOneTurn(newWorld, range_x, range_y)
-		}()
+func (world World) bareProcessOneTurn(newWorld World, workers *pool, rule Rule, topology Topology, tracker *activity) {
+	counter := world.newCounter(rule, topology)
+	if tracker != nil {
+		tracker.prepare()
 	}
 
-	wg.Wait()
+	workers.run(func(_ int, range_x, range_y Range) {
+		world.barePartialProcessOneTurn(newWorld, range_x, range_y, counter, tracker)
+	})
 }
 
-func (world World) barePartialProcessOneTurn(newWorld World, range_x, range_y Range) {
+func (world World) barePartialProcessOneTurn(newWorld World, range_x, range_y Range, counter counter, tracker *activity) {
 	for y := range_y.start; y < range_y.end; y++ {
-		for x := range_x.start; x < range_x.end; x++ {
-			world.bare_update_cell(newWorld, x, y)
+		if tracker == nil {
+			for x := range_x.start; x < range_x.end; x++ {
+				world.bare_update_cell(newWorld, x, y, counter)
+			}
+			continue
 		}
-	}
-}
 
-func (world World) bare_update_cell(newWorld World, x int, y int) {
-	neighbors := world.countNeigbours(x, y)
-	if world.world[y][x] != 0 {
-		if neighbors < 2 || neighbors > 3 {
-			newWorld.world[y][x] = 0
-		} else {
-			newWorld.world[y][x] = world.world[y][x]
-		}
-	} else {
-		if neighbors == 3 {
-			newWorld.world[y][x] = 255
-		} else {
-			newWorld.world[y][x] = 0
+		for column := tracker.column(range_x.start); column < tracker.column(range_x.end); column++ {
+			tile_x := tracker.columnRange(column)
+			changed := false
+			if tracker.isActive(column, y) {
+				for x := tile_x.start; x < tile_x.end; x++ {
+					if world.bare_update_cell(newWorld, x, y, counter) {
+						changed = true
+					}
+				}
+			} else {
+				copy(newWorld.world[y][tile_x.start:tile_x.end], world.world[y][tile_x.start:tile_x.end])
+			}
+			tracker.changed[y][column] = changed
 		}
 	}
 }
+
+// bare_update_cell writes the next value of a cell to newWorld, returning true if it changed.
+func (world World) bare_update_cell(newWorld World, x int, y int, counter counter) bool {
+	neighbors := counter.count(x, y)
+	newWorld.world[y][x] = counter.rule.next(world.world[y][x], neighbors)
+	return newWorld.world[y][x] != world.world[y][x]
+}
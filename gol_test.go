@@ -2,9 +2,6 @@ package main
 
 import (
 	"fmt"
-	"io/ioutil"
-	"strconv"
-	"strings"
 	"testing"
 
 	"uk.ac.bris.cs/gameoflife/gol"
@@ -85,45 +82,14 @@ func assertEqualBoard(t *testing.T, given, expected []util.Cell, p gol.Params) b
 	return true
 }
 
+// readAliveCells reads the cells alive in the Netpbm image at path, panicking unless it is width by height.
 func readAliveCells(path string, width, height int) []util.Cell {
-	data, ioError := ioutil.ReadFile(path)
-	util.Check(ioError)
-
-	fields := strings.Fields(string(data))
-
-	if fields[0] != "P5" {
-		panic("Not a pgm file")
-	}
-
-	imageWidth, _ := strconv.Atoi(fields[1])
-	if imageWidth != width {
+	image := readNetpbm(path)
+	if image.Width != width {
 		panic("Incorrect width")
 	}
-
-	imageHeight, _ := strconv.Atoi(fields[2])
-	if imageHeight != height {
+	if image.Height != height {
 		panic("Incorrect height")
 	}
-
-	maxval, _ := strconv.Atoi(fields[3])
-	if maxval != 255 {
-		panic("Incorrect maxval/bit depth")
-	}
-
-	image := []byte(fields[4])
-
-	var cells []util.Cell
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			cell := image[0]
-			if cell != 0 {
-				cells = append(cells, util.Cell{
-					X: x,
-					Y: y,
-				})
-			}
-			image = image[1:]
-		}
-	}
-	return cells
+	return image.AliveCells()
 }
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestMacrocell loads a glider in the north west quadrant of a Macrocell root, as Golly writes patterns left of and above 0, 0,
+// placing it on a larger board and checking it has moved one cell diagonally after 4 turns.
+func TestMacrocell(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-macrocell")
+	util.Check(err)
+	defer os.RemoveAll(dir)
+	p := assertGliderMoved(t, dir, "glider.mc", "[M2] (golly 2.0)\n#R B3/S23\n.*$..*$***$\n4 1 0 0 0\n")
+
+	util.Check(ioutil.WriteFile(p.InputPath, []byte("[M2] (golly 2.0)\n.*$..*$***$\n4 2 0 0 0\n"), 0644))
+	if _, ok := runError(p).(*gol.HeaderError); !ok {
+		t.Error("Expected a HeaderError for a node referring to a line after it")
+	}
+}
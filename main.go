@@ -3,10 +3,13 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 	"runtime"
+	"time"
 
 	"uk.ac.bris.cs/gameoflife/gol"
 	"uk.ac.bris.cs/gameoflife/sdl"
+	"uk.ac.bris.cs/gameoflife/util"
 )
 
 // main is the function called when starting Game of Life with 'go run .'
@@ -23,14 +26,89 @@ func main() {
 	flag.IntVar(
 		&params.ImageWidth,
 		"w",
-		512,
-		"Specify the width of the image. Defaults to 512.")
+		0,
+		"Specify the width of the image. Defaults to 512, or the width of the image given by -input.")
 
 	flag.IntVar(
 		&params.ImageHeight,
 		"h",
-		512,
-		"Specify the height of the image. Defaults to 512.")
+		0,
+		"Specify the height of the image. Defaults to 512, or the height of the image given by -input.")
+
+	flag.StringVar(
+		&params.InputPath,
+		"input",
+		"",
+		"Specify the Netpbm image, PNG, JPEG or GIF picture, or rle, cells, lif or mc pattern to load, detected by its content. Defaults to images/<w>x<h>.pgm.")
+
+	flag.Var(
+		&params.Dither,
+		"dither",
+		"Specify how a picture is converted to cells once scaled to the board: threshold, floyd-steinberg or ordered. Defaults to threshold.")
+
+	flag.BoolVar(
+		&params.Soup,
+		"soup",
+		false,
+		"Fill the board at random instead of loading an image, recording the soup in the names of output images. Defaults to false.")
+
+	flag.Int64Var(
+		&params.Seed,
+		"seed",
+		0,
+		"Specify the seed to draw the soup from. Defaults to a seed from the clock, which is printed.")
+
+	flag.Float64Var(
+		&params.Density,
+		"density",
+		0.5,
+		"Specify the proportion of alive cells in the soup. Defaults to 0.5.")
+
+	flag.IntVar(
+		&params.SoupWidth,
+		"soupw",
+		0,
+		"Specify the width of the rectangle at the centre of the board to draw the soup in. Defaults to the width of the board.")
+
+	flag.IntVar(
+		&params.SoupHeight,
+		"souph",
+		0,
+		"Specify the height of the rectangle at the centre of the board to draw the soup in. Defaults to the height of the board.")
+
+	flag.Var(
+		&params.Symmetry,
+		"symmetry",
+		"Specify the symmetry of the soup: C1, C2, C4, D2, D4 or D8. Defaults to C1, no symmetry.")
+
+	flag.IntVar(
+		&params.PatternX,
+		"patternx",
+		0,
+		"Specify the column to place the left edge of a pattern at. Defaults to 0.")
+
+	flag.IntVar(
+		&params.PatternY,
+		"patterny",
+		0,
+		"Specify the row to place the top edge of a pattern at. Defaults to 0.")
+
+	flag.StringVar(
+		&params.OutputDir,
+		"outdir",
+		"out",
+		"Specify the directory to write output images to. Defaults to out.")
+
+	flag.Var(
+		&params.OutputFormat,
+		"outformat",
+		"Specify the format to write output images in: pgm, rle, cells, life105, life106, mc or pbm. Defaults to pgm.")
+
+	flag.StringVar(
+		&params.OutputName,
+		"outname",
+		"",
+		"Specify the name of output images, which may contain {width}, {height}, {turn}, {rule}, {topology}, {timestamp} and {soup}. Defaults to {width}x{height}x{turn}.")
 
 	flag.IntVar(
 		&params.Turns,
@@ -38,6 +116,95 @@ func main() {
 		10000000000,
 		"Specify the number of turns to process. Defaults to 10000000000.")
 
+	flag.Var(
+		&params.Rule,
+		"rule",
+		"Specify the rule in B/S, B/S/C or Larger-than-Life notation, e.g. B36/S23 for HighLife. Defaults to B3/S23.")
+
+	flag.Var(
+		&params.Topology,
+		"topology",
+		"Specify the topology of the world: torus, plane, reflective, klein, cross or twisted+N. Defaults to torus.")
+
+	flag.Var(
+		&params.Engine,
+		"engine",
+		"Specify the engine used to evolve the world: byte, bit, hashlife or halo. Defaults to byte.")
+
+	flag.BoolVar(
+		&params.ActiveTiles,
+		"active",
+		false,
+		"Only recompute tiles of the world whose neighbourhood changed in the last turn. Defaults to false.")
+
+	flag.BoolVar(
+		&params.Tiles,
+		"tiles",
+		false,
+		"Share square tiles of the world between threads instead of a strip of rows each. Defaults to false.")
+
+	flag.IntVar(
+		&params.TileSize,
+		"tilesize",
+		0,
+		"Specify the width and height of the tiles shared between threads. Defaults to a size chosen from the image and threads.")
+
+	flag.StringVar(
+		&params.Server,
+		"server",
+		"",
+		"Specify the address of a broker to run the world on, e.g. 127.0.0.1:8030. Defaults to running locally.")
+
+	flag.BoolVar(
+		&params.Attach,
+		"attach",
+		false,
+		"Attach to the simulation left running on the broker given by -server instead of starting a new one. Defaults to false.")
+
+	flag.BoolVar(
+		&params.BatchFlips,
+		"batch",
+		true,
+		"Send the cells changed in each turn as one event instead of an event for every cell. Defaults to true.")
+
+	flag.StringVar(
+		&params.CheckpointDir,
+		"checkpoint",
+		"",
+		"Specify a directory to periodically save the world to, so the run can be resumed. Defaults to no checkpoints.")
+
+	flag.IntVar(
+		&params.CheckpointTurns,
+		"checkpointturns",
+		1000,
+		"Specify the number of turns between checkpoints. Defaults to 1000.")
+
+	flag.StringVar(
+		&params.Resume,
+		"resume",
+		"",
+		"Specify a checkpoint to resume the run from, taking its image size, turns, threads, rule, topology and engine.")
+
+	gifPath := flag.String(
+		"gif",
+		"",
+		"Specify a file to record the run to as an animated GIF. Defaults to no recording.")
+
+	gifEvery := flag.Int(
+		"gifevery",
+		1,
+		"Specify the number of turns between frames of the GIF. Defaults to 1.")
+
+	gifScale := flag.Int(
+		"gifscale",
+		1,
+		"Specify the width and height of each cell in the GIF in pixels. Defaults to 1.")
+
+	gifDelay := flag.Int(
+		"gifdelay",
+		10,
+		"Specify the time each frame of the GIF is shown for in hundredths of a second. Defaults to 10.")
+
 	noVis := flag.Bool(
 		"noVis",
 		false,
@@ -45,24 +212,72 @@ func main() {
 
 	flag.Parse()
 
+	//a soup without a seed is drawn from the clock, which is printed so the run can be repeated
+	seeded := false
+	flag.Visit(func(f *flag.Flag) {
+		seeded = seeded || f.Name == "seed"
+	})
+	if params.Soup && !seeded {
+		params.Seed = time.Now().UnixNano()
+	}
+
+	//a simulation being attached to has its own size, which is taken from the broker
+	if (params.InputPath == "" || params.Soup) && params.Resume == "" && !params.Attach {
+		if params.ImageWidth == 0 {
+			params.ImageWidth = 512
+		}
+		if params.ImageHeight == 0 {
+			params.ImageHeight = 512
+		}
+	}
+	params, err := gol.ResumeParams(params)
+	util.Check(err)
+	params, err = gol.InputParams(params)
+	util.Check(err)
+
 	fmt.Println("Threads:", params.Threads)
 	fmt.Println("Width:", params.ImageWidth)
 	fmt.Println("Height:", params.ImageHeight)
+	fmt.Println("Rule:", params.Rule)
+	fmt.Println("Topology:", params.Topology)
+	fmt.Println("Engine:", params.Engine)
+	if params.Soup {
+		fmt.Println("Seed:", params.Seed)
+	}
 
 	keyPresses := make(chan rune, 10)
 	events := make(chan gol.Event, 1000)
 
 	go gol.Run(params, events, keyPresses)
+
+	var visEvents <-chan gol.Event = events
+	var recorder *gol.GifRecorder
+	if *gifPath != "" {
+		recorder = gol.NewGifRecorder(params)
+		recorder.Every, recorder.Scale, recorder.Delay = *gifEvery, *gifScale, *gifDelay
+		visEvents = recorder.Record(events)
+	}
+
 	if !(*noVis) {
-		sdl.Run(params, events, keyPresses)
+		sdl.Run(params, visEvents, keyPresses)
 	} else {
 		complete := false
 		for !complete {
-			event := <-events
+			event := <-visEvents
 			switch event.(type) {
 			case gol.FinalTurnComplete:
 				complete = true
 			}
 		}
 	}
+
+	if recorder != nil {
+		//the gif is only complete once every event has been recorded
+		for range visEvents {
+		}
+		file, err := os.Create(*gifPath)
+		util.Check(err)
+		util.Check(recorder.Save(file))
+		util.Check(file.Close())
+	}
 }
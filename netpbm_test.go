@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// readNetpbm reads the Netpbm image at path.
+func readNetpbm(path string) util.Netpbm {
+	file, err := os.Open(path)
+	util.Check(err)
+	defer file.Close()
+	image, err := util.ReadNetpbm(file)
+	util.Check(err)
+	return image
+}
+
+// TestNetpbm writes the 64x64 image in every Netpbm format, along with a greymap with a maxval of 1
+// and one with two bytes to a pixel, then runs each for 100 turns, checking they give the same result as the image.
+func TestNetpbm(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-netpbm")
+	util.Check(err)
+	defer os.RemoveAll(dir)
+
+	image := readNetpbm("images/64x64.pgm")
+	expectedAlive := readAliveCells("check/images/64x64x100.pgm", 64, 64)
+
+	images := make(map[string][]byte)
+	for _, magic := range []string{"P1", "P2", "P4", "P5"} {
+		var buffer bytes.Buffer
+		image.Magic, image.Comments = magic, []string{"written as " + magic}
+		util.Check(image.Write(&buffer))
+		images[magic] = buffer.Bytes()
+	}
+	for _, maxval := range []int{1, 65535} {
+		var buffer bytes.Buffer
+		fmt.Fprintf(&buffer, "P5 # comment after the magic number\n64\n# comment between the width and height\n64 %d\n", maxval)
+		for _, row := range image.Pixels {
+			for _, pixel := range row {
+				if maxval == 1 {
+					buffer.WriteByte(pixel / 255)
+				} else {
+					buffer.Write([]byte{pixel, pixel})
+				}
+			}
+		}
+		images[fmt.Sprintf("maxval%d", maxval)] = buffer.Bytes()
+	}
+
+	for name, data := range images {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(dir, name+".pgm")
+			util.Check(ioutil.WriteFile(filename, data, 0644))
+			p := gol.Params{Turns: 100, Threads: 4, InputPath: filename, OutputDir: dir}
+			assertEqualBoard(t, runFinalCells(p), expectedAlive, p)
+		})
+	}
+}
+
+// TestNetpbmNonSquare loads a bitmap wider than it is tall, checking every cell ends up in the right place.
+func TestNetpbmNonSquare(t *testing.T) {
+	e, err := gol.Load(strings.NewReader("P1\n# a 5x3 bitmap\n5 3\n00000\n0000 1\n1000\n0\n"))
+	util.Check(err)
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 5; x++ {
+			expected := (x == 4 && y == 1) || (x == 0 && y == 2)
+			if e.Get(x, y) != expected {
+				t.Errorf("Expected cell %v, %v to be alive: %v", x, y, expected)
+			}
+		}
+	}
+
+	if _, err := gol.Load(strings.NewReader("P2\n5 3\n255\n0 0 0\n")); err == nil {
+		t.Error("Expected an error for a greymap with too few pixels")
+	}
+}
+
+// TestPbmOutput writes the 64x64 image as a bitmap after 100 turns, checking it gives the same cells as the pgm image.
+func TestPbmOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-netpbm")
+	util.Check(err)
+	defer os.RemoveAll(dir)
+
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 4, OutputDir: dir, OutputFormat: gol.PBM}
+	runFinalCells(p)
+	image := readNetpbm(filepath.Join(dir, "64x64x100.pbm"))
+	if image.Magic != "P4" {
+		t.Errorf("Expected a P4 bitmap, got %v", image.Magic)
+	}
+	assertEqualBoard(t, image.AliveCells(), readAliveCells("check/images/64x64x100.pgm", 64, 64), p)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestInputOutput loads an image from a path without giving its size and writes the output into another directory,
+// checking the size is read from the header and the output is named from the template.
+func TestInputOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-output")
+	util.Check(err)
+	defer os.RemoveAll(dir)
+
+	p := gol.Params{Turns: 1, Threads: 2, InputPath: "images/16x16.pgm", OutputDir: dir, OutputName: "{width}-{height}-{turn}-{rule}-{topology}"}
+	resolved, err := gol.InputParams(p)
+	util.Check(err)
+	if resolved.ImageWidth != 16 || resolved.ImageHeight != 16 {
+		t.Errorf("Expected the image size to be read as 16x16, got %vx%v", resolved.ImageWidth, resolved.ImageHeight)
+	}
+
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	var cells []util.Cell
+	filename := ""
+	for event := range events {
+		switch e := event.(type) {
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+		case gol.ImageOutputComplete:
+			filename = e.Filename
+		}
+	}
+	assertEqualBoard(t, cells, readAliveCells("check/images/16x16x1.pgm", 16, 16), resolved)
+
+	expected := filepath.Join(dir, "16-16-1-B3_S23-torus.pgm")
+	if filename != expected {
+		t.Errorf("Expected output to %v, got %v", expected, filename)
+	}
+	assertEqualBoard(t, readAliveCells(expected, 16, 16), cells, resolved)
+
+	p.OutputName = "{timestamp}"
+	events = make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	for event := range events {
+		if e, ok := event.(gol.ImageOutputComplete); ok {
+			filename = e.Filename
+		}
+	}
+	if !regexp.MustCompile(`^\d{8}-\d{6}\.pgm$`).MatchString(filepath.Base(filename)) {
+		t.Errorf("Expected output named by a timestamp, got %v", filename)
+	}
+
+	p.ImageWidth, p.ImageHeight = 64, 64
+	if _, ok := runError(p).(*gol.DimensionError); !ok {
+		t.Error("Expected a DimensionError for a 16x16 input given as 64x64")
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// writePicture writes img to filename with encode.
+func writePicture(filename string, img image.Image, encode func(file *os.File, img image.Image) error) {
+	file, err := os.Create(filename)
+	util.Check(err)
+	defer file.Close()
+	util.Check(encode(file, img))
+}
+
+// grayOf returns the pgm image at path as a greyscale picture with scale by scale pixels for every cell.
+func grayOf(path string, scale int) *image.Gray {
+	netpbm := readNetpbm(path)
+	img := image.NewGray(image.Rect(0, 0, netpbm.Width*scale, netpbm.Height*scale))
+	for y := 0; y < netpbm.Height*scale; y++ {
+		for x := 0; x < netpbm.Width*scale; x++ {
+			img.Pix[y*img.Stride+x] = netpbm.Pixels[y/scale][x/scale]
+		}
+	}
+	return img
+}
+
+// TestPicture loads the 64x64 image as a PNG, a GIF and a PNG twice the size scaled down to 64 cells wide,
+// checking each gives the same result as the image after 100 turns.
+func TestPicture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-picture")
+	util.Check(err)
+	defer os.RemoveAll(dir)
+
+	writePicture(filepath.Join(dir, "64x64.png"), grayOf("images/64x64.pgm", 1), func(file *os.File, img image.Image) error {
+		return png.Encode(file, img)
+	})
+	writePicture(filepath.Join(dir, "64x64.gif"), grayOf("images/64x64.pgm", 1), func(file *os.File, img image.Image) error {
+		return gif.Encode(file, img, nil)
+	})
+	writePicture(filepath.Join(dir, "128x128.png"), grayOf("images/64x64.pgm", 2), func(file *os.File, img image.Image) error {
+		return png.Encode(file, img)
+	})
+
+	expectedAlive := readAliveCells("check/images/64x64x100.pgm", 64, 64)
+	for _, name := range []string{"64x64.png", "64x64.gif", "128x128.png"} {
+		t.Run(name, func(t *testing.T) {
+			//the height is scaled with the width
+			p := gol.Params{ImageWidth: 64, Turns: 100, Threads: 4, InputPath: filepath.Join(dir, name), OutputDir: dir}
+			resolved, err := gol.InputParams(p)
+			util.Check(err)
+			if resolved.ImageWidth != 64 || resolved.ImageHeight != 64 {
+				t.Errorf("Expected the picture to be scaled to 64x64, got %vx%v", resolved.ImageWidth, resolved.ImageHeight)
+			}
+			assertEqualBoard(t, runFinalCells(p), expectedAlive, resolved)
+		})
+	}
+}
+
+// TestDither loads a uniform grey picture just darker than half way with each dither, checking threshold leaves
+// every cell dead, ordered dithering makes exactly half of them alive and Floyd-Steinberg close to half.
+// It also loads a JPEG which is white on the left and black on the right.
+func TestDither(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-picture")
+	util.Check(err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "gray.png")
+	grayPicture := image.NewGray(image.Rect(0, 0, 64, 64))
+	for i := range grayPicture.Pix {
+		grayPicture.Pix[i] = 127
+	}
+	writePicture(filename, grayPicture, func(file *os.File, img image.Image) error {
+		return png.Encode(file, img)
+	})
+
+	for _, dither := range []gol.Dither{gol.Threshold, gol.FloydSteinberg, gol.Ordered} {
+		p := gol.Params{Threads: 2, InputPath: filename, Dither: dither, OutputDir: dir}
+		alive := len(runFinalCells(p))
+		switch {
+		case dither == gol.Threshold && alive != 0:
+			t.Errorf("Expected no alive cells with threshold, got %v", alive)
+		case dither == gol.Ordered && alive != 64*64/2:
+			t.Errorf("Expected %v alive cells with ordered dithering, got %v", 64*64/2, alive)
+		case dither == gol.FloydSteinberg && (alive < 64*64*45/100 || alive > 64*64*55/100):
+			t.Errorf("Expected about %v alive cells with Floyd-Steinberg dithering, got %v", 64*64/2, alive)
+		}
+	}
+
+	halves := image.NewGray(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 32; x++ {
+			halves.Pix[y*halves.Stride+x] = 255
+		}
+	}
+	filename = filepath.Join(dir, "halves.jpg")
+	writePicture(filename, halves, func(file *os.File, img image.Image) error {
+		return jpeg.Encode(file, img, nil)
+	})
+	var expected []util.Cell
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 32; x++ {
+			expected = append(expected, util.Cell{X: x, Y: y})
+		}
+	}
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Threads: 2, InputPath: filename, OutputDir: dir}
+	assertEqualBoard(t, runFinalCells(p), expected, p)
+}
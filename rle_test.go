@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// glider is an RLE glider heading down and to the right, with the comment lines patterns are usually shared with.
+const glider = `#N Glider
+#O Richard K. Guy
+#C The smallest, most common, and first discovered spaceship.
+x = 3, y = 3, rule = B3/S23
+bob$2bo$3o!
+`
+
+// gliderMoved are the cells of the glider placed at 5, 5 after 4 turns, once it has moved one cell diagonally.
+var gliderMoved = []util.Cell{{X: 7, Y: 6}, {X: 8, Y: 7}, {X: 6, Y: 8}, {X: 7, Y: 8}, {X: 8, Y: 8}}
+
+// assertGliderMoved writes the glider pattern in content to name in dir and places it at 5, 5 on a 16x16 board,
+// checking it has moved one cell diagonally after 4 turns. It returns the params the pattern was run with.
+func assertGliderMoved(t *testing.T, dir, name, content string) gol.Params {
+	filename := filepath.Join(dir, name)
+	util.Check(ioutil.WriteFile(filename, []byte(content), 0644))
+
+	p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: 4, Threads: 2, InputPath: filename, PatternX: 5, PatternY: 5, OutputDir: dir}
+	assertEqualBoard(t, runFinalCells(p), gliderMoved, p)
+	return p
+}
+
+// assertRoundTrip writes a 64x64 image in format under HighLife, then runs the pattern without giving its rule,
+// or its size if sized is true as the format records it, checking it gives the same result as running the image,
+// including from the params returned by InputParams once the pattern has been removed.
+func assertRoundTrip(t *testing.T, format gol.Format, sized bool) {
+	dir, err := ioutil.TempDir("", "gol-"+format.String())
+	util.Check(err)
+	defer os.RemoveAll(dir)
+
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Threads: 4, OutputDir: dir, OutputFormat: format, OutputName: "pattern"}
+	util.Check(p.Rule.Set("B36/S23"))
+	runFinalCells(p)
+	written, err := filepath.Glob(filepath.Join(dir, "pattern.*"))
+	util.Check(err)
+
+	p.Turns, p.OutputName = 100, ""
+	expectedAlive := runFinalCells(p)
+
+	pattern := gol.Params{Turns: 100, Threads: 4, InputPath: written[0], OutputDir: dir}
+	if !sized {
+		pattern.ImageWidth, pattern.ImageHeight = 64, 64
+	}
+	resolved, err := gol.InputParams(pattern)
+	util.Check(err)
+	if resolved.ImageWidth != 64 || resolved.ImageHeight != 64 || resolved.Rule.String() != "B36/S23" {
+		t.Errorf("Expected a 64x64 pattern under B36/S23, got %vx%v under %v", resolved.ImageWidth, resolved.ImageHeight, resolved.Rule)
+	}
+	assertEqualBoard(t, runFinalCells(pattern), expectedAlive, p)
+
+	//the pattern read by InputParams is not read again
+	util.Check(os.Remove(written[0]))
+	assertEqualBoard(t, runFinalCells(resolved), expectedAlive, p)
+}
+
+// TestRle places an RLE glider on a larger board and checks it has moved one cell diagonally after 4 turns.
+func TestRle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-rle")
+	util.Check(err)
+	defer os.RemoveAll(dir)
+	p := assertGliderMoved(t, dir, "glider.rle", glider)
+
+	p.ImageWidth, p.ImageHeight, p.PatternX, p.PatternY = 4, 4, 2, 2
+	if _, ok := runError(p).(*gol.DimensionError); !ok {
+		t.Error("Expected a DimensionError for a pattern beyond the edge of the board")
+	}
+
+	util.Check(ioutil.WriteFile(p.InputPath, []byte("bob$2bo$3o!\n"), 0644))
+	if _, ok := runError(p).(*gol.HeaderError); !ok {
+		t.Error("Expected a HeaderError for a pattern without a header")
+	}
+}
+
+// TestPatternOutput writes a 64x64 image in each pattern format recording the rule, then runs each pattern,
+// checking it gives the same result as running the image.
+func TestPatternOutput(t *testing.T) {
+	tests := []struct {
+		format gol.Format
+		// sized is true if the format records the size of the board
+		sized bool
+	}{
+		{gol.RLE, true},
+		{gol.Macrocell, false},
+	}
+	for _, test := range tests {
+		t.Run(test.format.String(), func(t *testing.T) {
+			assertRoundTrip(t, test.format, test.sized)
+		})
+	}
+}
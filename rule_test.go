@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestParseRule checks that rules in the common notations are parsed and printed in canonical B/S notation.
+func TestParseRule(t *testing.T) {
+	valid := map[string]string{
+		"B3/S23":                      "B3/S23",
+		"b36/s23":                     "B36/S23",
+		"S23/B3":                      "B3/S23",
+		"23/36":                       "B36/S23",
+		"B3678/S34678":                "B3678/S34678",
+		"B2/S":                        "B2/S",
+		"B2/S/C3":                     "B2/S/C3",
+		"/2/3":                        "B2/S/C3",
+		"345/2/4":                     "B2/S345/C4",
+		"B3/S23/C2":                   "B3/S23",
+		"R5,C0,M1,S34..58,B34..45,NM": "R5,C0,M1,S34..58,B34..45,NM",
+		"r2,b3..5,s2..6,nn":           "R2,C0,M0,S2..6,B3..5,NN",
+		"R3,C4,M0,S5..9,B6..7,NM":     "R3,C4,M0,S5..9,B6..7,NM",
+		"R1,C0,M0,S2..3,B3..3,NM":     "B3/S23",
+	}
+	for notation, expected := range valid {
+		rule, err := gol.ParseRule(notation)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", notation, err)
+		} else if rule.String() != expected {
+			t.Errorf("%q: expected %v, got %v", notation, expected, rule)
+		}
+	}
+
+	for _, notation := range []string{"", "B3", "B3/S23/C1", "B3/S23/C257", "B3/S23/C3/C4", "B9/S23",
+		"R0,C0,M0,S2..3,B3..3,NM", "R11,C0,M0,S2..3,B3..3,NM", "R1,C0,M0,S2..3,NM", "R1,C0,M0,S2..9,B3..3,NM", "R2,S3..2,B3..3", "R2,S2..3,B3..3,NX", "B3/B23", "X3/S23"} {
+		if _, err := gol.ParseRule(notation); err == nil {
+			t.Errorf("%q: expected an error", notation)
+		}
+	}
+
+	var unset gol.Rule
+	if unset.String() != "B3/S23" {
+		t.Errorf("zero rule: expected B3/S23, got %v", unset)
+	}
+}
+
+// replicatorCells is the HighLife replicator, with its top left corner at 0, 0.
+var replicatorCells = []util.Cell{
+	{X: 2, Y: 0}, {X: 3, Y: 0}, {X: 4, Y: 0}, {X: 1, Y: 1}, {X: 4, Y: 1}, {X: 0, Y: 2}, {X: 4, Y: 2},
+	{X: 0, Y: 3}, {X: 3, Y: 3}, {X: 0, Y: 4}, {X: 1, Y: 4}, {X: 2, Y: 4},
+}
+
+// TestRule checks that an explicit B3/S23 rule gives the same results as the default,
+// that a rule with no births or survivals empties the board,
+// and that under HighLife the replicator has made two copies of itself 2 cells either side of it after 12 turns.
+func TestRule(t *testing.T) {
+	for _, turns := range []int{0, 1, 100} {
+		p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: turns, Threads: 4}
+		err := p.Rule.Set("B3/S23")
+		util.Check(err)
+		expectedAlive := readAliveCells(
+			"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, turns),
+			p.ImageWidth,
+			p.ImageHeight,
+		)
+		t.Run(fmt.Sprintf("%v-%d", p.Rule, turns), func(t *testing.T) {
+			assertEqualBoard(t, runFinalCells(p), expectedAlive, p)
+		})
+	}
+
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 1, Threads: 4}
+	err := p.Rule.Set("B/S")
+	util.Check(err)
+	t.Run(p.Rule.String(), func(t *testing.T) {
+		if cells := runFinalCells(p); len(cells) != 0 {
+			t.Errorf("expected no alive cells, got %v", len(cells))
+		}
+	})
+
+	expectedAlive := append(offset(replicatorCells, 12, 12), offset(replicatorCells, 16, 16)...)
+	for _, engine := range []gol.EngineKind{gol.ByteEngine, gol.BitEngine} {
+		for _, threads := range []int{1, 4} {
+			p := gol.Params{ImageWidth: 32, ImageHeight: 32, Turns: 12, Threads: threads, Engine: engine}
+			util.Check(p.Rule.Set("B36/S23"))
+			t.Run(fmt.Sprintf("%v-replicator-%v-%d", p.Rule, engine, threads), func(t *testing.T) {
+				assertEqualBoard(t, runCells(p, offset(replicatorCells, 14, 14)), expectedAlive, p)
+			})
+		}
+	}
+}
+
+// TestGenerations checks that alive cells under Brian's Brain decay through a grey state before dying.
+func TestGenerations(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 2, Threads: 4}
+	err := p.Rule.Set("B2/S/C3")
+	util.Check(err)
+	initial := readAliveCells("check/images/64x64x0.pgm", p.ImageWidth, p.ImageHeight)
+
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	board := make(map[util.Cell]byte)
+	for event := range events {
+		switch e := event.(type) {
+		case gol.CellFlipped:
+			t.Fatalf("unexpected CellFlipped event for a Generations rule at %v", e.Cell)
+		case gol.CellStateChanged:
+			if e.CompletedTurns == 1 && board[e.Cell] == 255 && e.Value != 127 {
+				t.Errorf("expected alive cell %v to decay to 127, got %v", e.Cell, e.Value)
+			}
+			board[e.Cell] = e.Value
+		case gol.TurnComplete:
+			if e.CompletedTurns == 1 {
+				for _, cell := range initial {
+					if board[cell] != 127 {
+						t.Fatalf("expected initially alive cell %v to be decaying after 1 turn, got %v", cell, board[cell])
+					}
+				}
+			}
+		case gol.FinalTurnComplete:
+			alive := 0
+			for _, value := range board {
+				if value == 255 {
+					alive++
+				}
+			}
+			if alive != len(e.Alive) {
+				t.Errorf("expected %v alive cells from events, got %v", len(e.Alive), alive)
+			}
+		}
+	}
+}
+
+// TestLargerThanLife checks that Conway's Game of Life written as a Larger-than-Life rule,
+// which counts each cell as its own neighbour, gives the same results as the default rule,
+// and that larger radii give the same results as counting every neighbour one at a time, with any number of threads.
+func TestLargerThanLife(t *testing.T) {
+	for _, turns := range []int{0, 1, 100} {
+		p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: turns, Threads: 4}
+		err := p.Rule.Set("R1,C0,M1,S3..4,B3..3,NM")
+		util.Check(err)
+		expectedAlive := readAliveCells(
+			"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, turns),
+			p.ImageWidth,
+			p.ImageHeight,
+		)
+		t.Run(fmt.Sprintf("%v-%d", p.Rule, turns), func(t *testing.T) {
+			assertEqualBoard(t, runFinalCells(p), expectedAlive, p)
+		})
+	}
+
+	rules := []struct {
+		notation string
+		radius   int
+		// vonNeumann and middle are the neighbourhood and whether it includes the cell itself
+		vonNeumann, middle       bool
+		minSurvival, maxSurvival int
+		minBirth, maxBirth       int
+	}{
+		{"R5,C0,M1,S34..58,B34..45,NM", 5, false, true, 34, 58, 34, 45},
+		{"R4,C0,M0,S6..18,B9..14,NN", 4, true, false, 6, 18, 9, 14},
+	}
+	topologies := map[string]func(x, y int) (int, int, bool){
+		"torus": func(x, y int) (int, int, bool) {
+			return (x + 64) % 64, (y + 64) % 64, true
+		},
+		"plane": func(x, y int) (int, int, bool) {
+			return x, y, x >= 0 && x < 64 && y >= 0 && y < 64
+		},
+	}
+	initial := readAliveCells("images/64x64.pgm", 64, 64)
+	for _, rule := range rules {
+		for topology, locate := range topologies {
+			p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 10, Threads: 1}
+			util.Check(p.Rule.Set(rule.notation))
+			util.Check(p.Topology.Set(topology))
+			next := largerThanLife(locate, rule.radius, rule.vonNeumann, rule.middle, rule.minSurvival, rule.maxSurvival, rule.minBirth, rule.maxBirth)
+			expectedAlive := referenceTurns(p, initial, next)
+			if len(expectedAlive) == 0 {
+				t.Fatalf("Expected cells to be alive after %v turns of %v", p.Turns, p.Rule)
+			}
+			for _, threads := range []int{1, 3, 8} {
+				p.Threads = threads
+				t.Run(fmt.Sprintf("%v-%v-%d", p.Rule, p.Topology, threads), func(t *testing.T) {
+					assertEqualBoard(t, runFinalCells(p), expectedAlive, p)
+				})
+			}
+		}
+	}
+}
+
+// largerThanLife returns the next function of referenceTurns for a two state Larger-than-Life rule,
+// counting the cells within radius one at a time, in a diamond for von Neumann neighbourhoods.
+// Cells survive with minSurvival to maxSurvival counted and are born with minBirth to maxBirth counted.
+func largerThanLife(locate func(x, y int) (int, int, bool), radius int, vonNeumann, middle bool, minSurvival, maxSurvival, minBirth, maxBirth int) func(board [][]bool, x, y int) bool {
+	return func(board [][]bool, x, y int) bool {
+		neighbours := 0
+		for dy := -radius; dy <= radius; dy++ {
+			for dx := -radius; dx <= radius; dx++ {
+				if (dx == 0 && dy == 0 && !middle) || (vonNeumann && abs(dx)+abs(dy) > radius) {
+					continue
+				}
+				if nx, ny, ok := locate(x+dx, y+dy); ok && board[ny][nx] {
+					neighbours++
+				}
+			}
+		}
+		if board[y][x] {
+			return neighbours >= minSurvival && neighbours <= maxSurvival
+		}
+		return neighbours >= minBirth && neighbours <= maxBirth
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func runFinalCells(p gol.Params) []util.Cell {
+	events := make(chan gol.Event)
+	go gol.Run(p, events, nil)
+	var cells []util.Cell
+	for event := range events {
+		switch e := event.(type) {
+		case gol.FinalTurnComplete:
+			cells = e.Alive
+		}
+	}
+	return cells
+}
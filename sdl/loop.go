@@ -24,6 +24,8 @@ sdlLoop:
 					keyPresses <- 'q'
 				case sdl.K_k:
 					keyPresses <- 'k'
+				case sdl.K_d:
+					keyPresses <- 'd'
 				}
 			}
 		}
@@ -36,6 +38,16 @@ sdlLoop:
 			switch e := event.(type) {
 			case gol.CellFlipped:
 				w.FlipPixel(e.Cell.X, e.Cell.Y)
+			case gol.CellStateChanged:
+				w.SetPixelValue(e.Cell.X, e.Cell.Y, e.Value)
+			case gol.CellsFlipped:
+				for i, cell := range e.Cells {
+					if e.Values != nil {
+						w.SetPixelValue(cell.X, cell.Y, e.Values[i])
+					} else {
+						w.FlipPixel(cell.X, cell.Y)
+					}
+				}
 			case gol.TurnComplete:
 				w.RenderFrame()
 			case gol.FinalTurnComplete:
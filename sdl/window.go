@@ -75,6 +75,18 @@ func (w *Window) SetPixel(x, y int) {
 	w.pixels[4*(y*width+x)+3] = 0xFF
 }
 
+func (w *Window) SetPixelValue(x, y int, value byte) {
+	if x < 0 || y < 0 || x >= int(w.Width) || y >= int(w.Height) {
+		panic(fmt.Sprintf("CellStateChanged event at (%d, %d) is outside the bounds of the window.", x, y))
+	}
+
+	width := int(w.Width)
+	w.pixels[4*(y*width+x)+0] = value
+	w.pixels[4*(y*width+x)+1] = value
+	w.pixels[4*(y*width+x)+2] = value
+	w.pixels[4*(y*width+x)+3] = value
+}
+
 func (w *Window) FlipPixel(x, y int) {
 	if x < 0 || y < 0 || x >= int(w.Width) || y >= int(w.Height) {
 		panic(fmt.Sprintf("CellFlipped event at (%d, %d) is outside the bounds of the window.", x, y))
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestEngineLoad loads a 64x64 image into an engine and steps it 100 turns, switching to hashlife half way,
+// checking the cells alive against the check image.
+func TestEngineLoad(t *testing.T) {
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100}
+	file, err := os.Open("images/64x64.pgm")
+	util.Check(err)
+	defer file.Close()
+	engine, err := gol.Load(file)
+	util.Check(err)
+	defer engine.Close()
+
+	engine.Step(50)
+	util.Check(engine.Configure(gol.Params{Engine: gol.HashLifeEngine}))
+	engine.Step(50)
+
+	if engine.Turn() != 100 {
+		t.Errorf("Expected 100 turns, got %v", engine.Turn())
+	}
+	expectedAlive := readAliveCells("check/images/64x64x100.pgm", p.ImageWidth, p.ImageHeight)
+	assertEqualBoard(t, engine.Alive(), expectedAlive, p)
+	if engine.Population() != len(expectedAlive) {
+		t.Errorf("Expected a population of %v, got %v", len(expectedAlive), engine.Population())
+	}
+}
+
+// TestEngineEdit sets cells of an empty world, checking a blinker oscillates, that Configure rejects
+// params the engine cannot run without changing it, and that a saved world loads back unchanged.
+func TestEngineEdit(t *testing.T) {
+	engine := gol.New(5, 5)
+	defer engine.Close()
+	for x := 1; x <= 3; x++ {
+		engine.Set(x, 2, true)
+	}
+
+	vertical := []util.Cell{{X: 2, Y: 1}, {X: 2, Y: 2}, {X: 2, Y: 3}}
+	horizontal := []util.Cell{{X: 1, Y: 2}, {X: 2, Y: 2}, {X: 3, Y: 2}}
+	for turn := 1; turn <= 4; turn++ {
+		engine.Step(1)
+		expected := vertical
+		if turn%2 == 0 {
+			expected = horizontal
+		}
+		for _, cell := range expected {
+			if !engine.Get(cell.X, cell.Y) {
+				t.Fatalf("Expected %v to be alive at turn %v, got %v", cell, turn, engine.Alive())
+			}
+		}
+		if engine.Population() != 3 {
+			t.Fatalf("Expected 3 cells alive at turn %v, got %v", turn, engine.Population())
+		}
+	}
+
+	if err := engine.Configure(gol.Params{Engine: gol.HashLifeEngine}); err == nil {
+		t.Error("Expected an error configuring hashlife for a 5x5 world")
+	}
+	engine.Set(0, 0, true)
+	engine.Set(2, 1, false)
+	engine.Step(1)
+
+	var saved bytes.Buffer
+	util.Check(engine.Save(&saved))
+	loaded, err := gol.Load(&saved)
+	util.Check(err)
+	defer loaded.Close()
+	p := gol.Params{ImageWidth: 5, ImageHeight: 5}
+	assertEqualBoard(t, loaded.Alive(), engine.Alive(), p)
+
+	if _, err := gol.Load(bytes.NewBufferString("P6\n5 5\n255\n")); err == nil {
+		t.Error("Expected an error loading an image which is not a Netpbm bitmap or greymap")
+	}
+}
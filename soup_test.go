@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// sameCells returns whether a and b hold the same cells in any order.
+func sameCells(a, b []util.Cell) bool {
+	cells := make(map[util.Cell]bool)
+	for _, cell := range a {
+		cells[cell] = true
+	}
+	for _, cell := range b {
+		if !cells[cell] {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+// TestSoup draws 64x64 soups, checking the same seed gives the same soup and another seed a different one,
+// that about half the cells are alive, and that the soup is recorded in the name of the output image.
+func TestSoup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-soup")
+	util.Check(err)
+	defer os.RemoveAll(dir)
+
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Threads: 2, Soup: true, Seed: 42, OutputDir: dir}
+	first := runFinalCells(p)
+	assertEqualBoard(t, runFinalCells(p), first, p)
+	if len(first) < 64*64*45/100 || len(first) > 64*64*55/100 {
+		t.Errorf("Expected about %v alive cells, got %v", 64*64/2, len(first))
+	}
+	assertEqualBoard(t, readAliveCells(filepath.Join(dir, "64x64x0-soup42-0.5-C1.pgm"), 64, 64), first, p)
+
+	p.Seed = 43
+	if sameCells(runFinalCells(p), first) {
+		t.Error("Expected a different soup for a different seed")
+	}
+
+	p.Density = 0.1
+	if alive := runFinalCells(p); len(alive) < 64*64*5/100 || len(alive) > 64*64*15/100 {
+		t.Errorf("Expected about %v alive cells at a density of 0.1, got %v", 64*64/10, len(alive))
+	}
+}
+
+// TestSoupSymmetry draws a 20x20 soup with each symmetry at the centre of a 64x64 board,
+// checking the board has the symmetry after 0 and 10 turns and that nothing is drawn outside the soup.
+func TestSoupSymmetry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gol-soup")
+	util.Check(err)
+	defer os.RemoveAll(dir)
+
+	//the maps of the board onto itself under each symmetry, about its centre
+	halfTurn := func(c util.Cell) util.Cell { return util.Cell{X: 63 - c.X, Y: 63 - c.Y} }
+	quarterTurn := func(c util.Cell) util.Cell { return util.Cell{X: 63 - c.Y, Y: c.X} }
+	mirror := func(c util.Cell) util.Cell { return util.Cell{X: 63 - c.X, Y: c.Y} }
+	flip := func(c util.Cell) util.Cell { return util.Cell{X: c.X, Y: 63 - c.Y} }
+	transpose := func(c util.Cell) util.Cell { return util.Cell{X: c.Y, Y: c.X} }
+	symmetries := map[gol.Symmetry][]func(util.Cell) util.Cell{
+		gol.C2: {halfTurn},
+		gol.C4: {quarterTurn},
+		gol.D2: {mirror},
+		gol.D4: {mirror, flip},
+		gol.D8: {quarterTurn, mirror, transpose},
+	}
+
+	for symmetry, maps := range symmetries {
+		t.Run(symmetry.String(), func(t *testing.T) {
+			for _, turns := range []int{0, 10} {
+				p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: turns, Threads: 2, Soup: true, Seed: 7,
+					SoupWidth: 20, SoupHeight: 20, Symmetry: symmetry, OutputDir: dir}
+				alive := runFinalCells(p)
+				if turns == 0 && len(alive) == 0 {
+					t.Fatal("Expected alive cells in the soup")
+				}
+				for _, m := range maps {
+					mapped := make([]util.Cell, len(alive))
+					for i, cell := range alive {
+						mapped[i] = m(cell)
+					}
+					assertEqualBoard(t, mapped, alive, p)
+				}
+				if turns > 0 {
+					continue
+				}
+				for _, cell := range alive {
+					if cell.X < 22 || cell.X >= 42 || cell.Y < 22 || cell.Y >= 42 {
+						t.Fatalf("Expected every alive cell in the 20x20 soup at the centre, got %v", cell)
+					}
+				}
+			}
+		})
+	}
+
+	p := gol.Params{ImageWidth: 64, ImageHeight: 64, Threads: 2, Soup: true, SoupWidth: 20, SoupHeight: 10, Symmetry: gol.C4, OutputDir: dir}
+	if runError(p) == nil {
+		t.Error("Expected an error for C4 symmetry on a soup which is not square")
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// TestParseTopology checks that every topology name is parsed and printed back unchanged.
+func TestParseTopology(t *testing.T) {
+	for _, name := range []string{"torus", "plane", "reflective", "klein", "cross", "twisted+3", "twisted-1"} {
+		topology, err := gol.ParseTopology(name)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", name, err)
+		} else if topology.String() != name {
+			t.Errorf("%q: expected %v, got %v", name, name, topology)
+		}
+	}
+
+	for _, name := range []string{"", "sphere", "twisted", "twisted+x"} {
+		if _, err := gol.ParseTopology(name); err == nil {
+			t.Errorf("%q: expected an error", name)
+		}
+	}
+}
+
+// TestTopology checks that topologies equivalent to a torus give the expected results,
+// and that the other topologies give the same results with any number of threads
+// and record their topology in the output filename.
+func TestTopology(t *testing.T) {
+	for _, name := range []string{"torus", "twisted+0", "twisted+64"} {
+		for _, turns := range []int{1, 100} {
+			p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: turns, Threads: 4}
+			err := p.Topology.Set(name)
+			util.Check(err)
+			expectedAlive := readAliveCells(
+				"check/images/"+fmt.Sprintf("%vx%vx%v.pgm", p.ImageWidth, p.ImageHeight, turns),
+				p.ImageWidth,
+				p.ImageHeight,
+			)
+			t.Run(fmt.Sprintf("%v-%d", name, turns), func(t *testing.T) {
+				assertEqualBoard(t, runFinalCells(p), expectedAlive, p)
+			})
+		}
+	}
+
+	for _, name := range []string{"plane", "reflective", "klein", "cross", "twisted+5"} {
+		p := gol.Params{ImageWidth: 64, ImageHeight: 64, Turns: 100, Threads: 1}
+		err := p.Topology.Set(name)
+		util.Check(err)
+		expectedAlive := runFinalCells(p)
+		for _, threads := range []int{3, 8} {
+			p.Threads = threads
+			t.Run(fmt.Sprintf("%v-%d", name, threads), func(t *testing.T) {
+				assertEqualBoard(t, runFinalCells(p), expectedAlive, p)
+			})
+		}
+
+		filename := fmt.Sprintf("out/%vx%vx%v-%v.pgm", p.ImageWidth, p.ImageHeight, p.Turns, name)
+		if _, err := os.Stat(filename); err != nil {
+			t.Errorf("expected output file %v: %v", filename, err)
+		}
+	}
+}
+
+// gliderCells is a glider heading down and to the right, with the top left corner of its bounding box at 0, 0.
+var gliderCells = []util.Cell{{X: 1, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2}}
+
+// offset returns cells moved right by dx and down by dy.
+func offset(cells []util.Cell, dx, dy int) []util.Cell {
+	moved := make([]util.Cell, len(cells))
+	for i, cell := range cells {
+		moved[i] = util.Cell{X: cell.X + dx, Y: cell.Y + dy}
+	}
+	return moved
+}
+
+// runCells makes cells alive on a world of the size in p, then evolves it by p.Turns under the rule and topology in p.
+func runCells(p gol.Params, cells []util.Cell) []util.Cell {
+	e := gol.New(p.ImageWidth, p.ImageHeight)
+	defer e.Close()
+	util.Check(e.Configure(p))
+	for _, cell := range cells {
+		e.Set(cell.X, cell.Y, true)
+	}
+	e.Step(p.Turns)
+	return e.Alive()
+}
+
+// referenceTurns makes cells alive on a world of the size in p, then evolves it by p.Turns,
+// with next returning whether the cell at x, y of board is alive after each turn.
+// It is slow but independent of how the engines count neighbours.
+func referenceTurns(p gol.Params, cells []util.Cell, next func(board [][]bool, x, y int) bool) []util.Cell {
+	board := make([][]bool, p.ImageHeight)
+	for y := range board {
+		board[y] = make([]bool, p.ImageWidth)
+	}
+	for _, cell := range cells {
+		board[cell.Y][cell.X] = true
+	}
+
+	for turn := 0; turn < p.Turns; turn++ {
+		nextBoard := make([][]bool, p.ImageHeight)
+		for y := range nextBoard {
+			nextBoard[y] = make([]bool, p.ImageWidth)
+			for x := range nextBoard[y] {
+				nextBoard[y][x] = next(board, x, y)
+			}
+		}
+		board = nextBoard
+	}
+
+	var alive []util.Cell
+	for y, row := range board {
+		for x, cell := range row {
+			if cell {
+				alive = append(alive, util.Cell{X: x, Y: y})
+			}
+		}
+	}
+	return alive
+}
+
+// conway returns the next function of referenceTurns for Conway's Game of Life,
+// with locate returning the cell found at x, y beyond the edges of the board, or false if it is dead.
+func conway(locate func(x, y int) (int, int, bool)) func(board [][]bool, x, y int) bool {
+	return func(board [][]bool, x, y int) bool {
+		neighbours := 0
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if dx == 0 && dy == 0 {
+					continue
+				}
+				if nx, ny, ok := locate(x+dx, y+dy); ok && board[ny][nx] {
+					neighbours++
+				}
+			}
+		}
+		return neighbours == 3 || (neighbours == 2 && board[y][x])
+	}
+}
+
+// TestTopologyEdges follows a glider over the edges of each topology.
+// On a plane it crashes into the corner and dies as a block, and off a reflective edge it meets its own reflection,
+// both checked against counting neighbours one at a time.
+// Over the seams of a klein bottle, cross surface and twisted torus it carries on as a glider,
+// coming back flipped or shifted.
+func TestTopologyEdges(t *testing.T) {
+	p := gol.Params{ImageWidth: 10, ImageHeight: 10, Turns: 60}
+	util.Check(p.Topology.Set("plane"))
+	plane := func(x, y int) (int, int, bool) {
+		return x, y, x >= 0 && x < 10 && y >= 0 && y < 10
+	}
+	block := []util.Cell{{X: 8, Y: 8}, {X: 9, Y: 8}, {X: 8, Y: 9}, {X: 9, Y: 9}}
+	assertEqualBoard(t, referenceTurns(p, offset(gliderCells, 1, 1), conway(plane)), block, p)
+	assertEqualBoard(t, runCells(p, offset(gliderCells, 1, 1)), block, p)
+
+	//a taller board, so the glider reaches the right edge long before the bottom
+	p = gol.Params{ImageWidth: 10, ImageHeight: 30, Turns: 60}
+	util.Check(p.Topology.Set("reflective"))
+	mirror := func(v, limit int) int {
+		if v < 0 {
+			return -1 - v
+		}
+		if v >= limit {
+			return 2*limit - 1 - v
+		}
+		return v
+	}
+	reflective := func(x, y int) (int, int, bool) {
+		return mirror(x, 10), mirror(y, 30), true
+	}
+	for _, turns := range []int{20, 40, 60} {
+		p.Turns = turns
+		assertEqualBoard(t, runCells(p, offset(gliderCells, 1, 1)), referenceTurns(p, offset(gliderCells, 1, 1), conway(reflective)), p)
+	}
+
+	tests := []struct {
+		name     string
+		start    util.Cell
+		turns    int
+		expected []util.Cell
+	}{
+		//after 64 turns the glider has moved 16 cells down and right, crossing the bottom and right edges once each
+		{"torus", util.Cell{X: 2, Y: 2}, 64, offset(gliderCells, 2, 2)},
+		//the bottom edge flips it horizontally, into a glider heading down and to the left
+		{"klein", util.Cell{X: 2, Y: 2}, 64, []util.Cell{{X: 12, Y: 2}, {X: 11, Y: 3}, {X: 13, Y: 4}, {X: 12, Y: 4}, {X: 11, Y: 4}}},
+		//the bottom edge moves it 5 cells right
+		{"twisted+5", util.Cell{X: 2, Y: 2}, 64, offset(gliderCells, 7, 2)},
+		//the corners of a cross surface are not like the rest of the world, so each edge is crossed away from them
+		//the bottom edge flips it horizontally
+		{"cross", util.Cell{X: 2, Y: 8}, 32, []util.Cell{{X: 4, Y: 0}, {X: 3, Y: 1}, {X: 5, Y: 2}, {X: 4, Y: 2}, {X: 3, Y: 2}}},
+		//the right edge flips it vertically, into a glider heading up and to the right
+		{"cross", util.Cell{X: 8, Y: 2}, 32, []util.Cell{{X: 1, Y: 5}, {X: 2, Y: 4}, {X: 0, Y: 3}, {X: 1, Y: 3}, {X: 2, Y: 3}}},
+	}
+	for _, test := range tests {
+		p := gol.Params{ImageWidth: 16, ImageHeight: 16, Turns: test.turns}
+		util.Check(p.Topology.Set(test.name))
+		t.Run(fmt.Sprintf("%v-%d-%d", test.name, test.start.X, test.start.Y), func(t *testing.T) {
+			assertEqualBoard(t, runCells(p, offset(gliderCells, test.start.X, test.start.Y)), test.expected, p)
+		})
+	}
+}
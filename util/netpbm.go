@@ -0,0 +1,310 @@
+package util
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// netpbmLineLength is the longest line written in the pixels of the plain formats.
+const netpbmLineLength = 70
+
+// Netpbm is an image in one of the Netpbm formats: P1 and P4 bitmaps, or P2 and P5 greymaps.
+type Netpbm struct {
+	// Magic is the magic number of the format, one of P1, P2, P4 or P5
+	Magic  string
+	Width  int
+	Height int
+	// Comments are the comments in the header, without the # and surrounding space
+	Comments []string
+	// Pixels are the rows of the image, with greymaps scaled from their maxval to 0-255 and the set bits of bitmaps as 255
+	Pixels [][]byte
+}
+
+// PixelError is returned by ReadNetpbm when the header of an image is correct but its pixels are not,
+// either ending early or holding values the format does not allow.
+type PixelError struct {
+	Err error
+}
+
+func (err *PixelError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *PixelError) Unwrap() error {
+	return err.Err
+}
+
+// netpbmScanner reads the whitespace separated tokens of a Netpbm file, collecting the comments between them.
+type netpbmScanner struct {
+	reader   *bufio.Reader
+	comments []string
+}
+
+// ReadNetpbm reads a P1, P2, P4 or P5 image from reader. Comments may appear anywhere in the header,
+// and greymaps may have any maxval up to 65535.
+func ReadNetpbm(reader io.Reader) (Netpbm, error) {
+	s := netpbmScanner{reader: bufio.NewReader(reader)}
+	var image Netpbm
+
+	magic := make([]byte, 2)
+	if _, err := io.ReadFull(s.reader, magic); err != nil || magic[0] != 'P' || !strings.ContainsRune("1245", rune(magic[1])) {
+		return image, errors.New("not a netpbm image, expected P1, P2, P4 or P5")
+	}
+	image.Magic = string(magic)
+	bitmap := image.Magic == "P1" || image.Magic == "P4"
+
+	//the width, height and maxval, which bitmaps leave out
+	header := []int{0, 0, 1}
+	count := 3
+	if bitmap {
+		count = 2
+	}
+	for i := 0; i < count; i++ {
+		token, err := s.token(i == count-1 && (image.Magic == "P4" || image.Magic == "P5"))
+		if err != nil {
+			return image, fmt.Errorf("incorrect header: %v", err)
+		}
+		header[i], err = strconv.Atoi(token)
+		if err != nil || header[i] <= 0 {
+			return image, fmt.Errorf("incorrect width, height or maxval %q", token)
+		}
+	}
+	image.Width, image.Height = header[0], header[1]
+	maxval := header[2]
+	if maxval > 65535 {
+		return image, fmt.Errorf("incorrect maxval %v, expected at most 65535", maxval)
+	}
+
+	image.Pixels = make([][]byte, image.Height)
+	for y := range image.Pixels {
+		image.Pixels[y] = make([]byte, image.Width)
+	}
+	err := s.readPixels(image, maxval)
+	image.Comments = s.comments
+	if err != nil {
+		return image, &PixelError{Err: err}
+	}
+	return image, nil
+}
+
+// token returns the next token, skipping whitespace and comments.
+// The single whitespace character ending the header of the binary formats is consumed if last is true,
+// while any other character ending a token is left to be read.
+func (s *netpbmScanner) token(last bool) (string, error) {
+	var token []byte
+	for {
+		c, err := s.reader.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(token) > 0 {
+				return string(token), nil
+			}
+			return "", err
+		}
+		switch {
+		case c == '#' && len(token) == 0:
+			if err := s.comment(); err != nil {
+				return "", err
+			}
+		case isNetpbmSpace(c) && len(token) == 0:
+		case isNetpbmSpace(c):
+			if !last {
+				err = s.reader.UnreadByte()
+			}
+			return string(token), err
+		case c == '#':
+			//a comment ending the last token of the header is ended by the newline separating the pixels
+			if last {
+				return string(token), s.comment()
+			}
+			return string(token), s.reader.UnreadByte()
+		default:
+			token = append(token, c)
+		}
+	}
+}
+
+// comment reads the rest of a comment once its # has been read.
+func (s *netpbmScanner) comment() error {
+	line, err := s.reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	s.comments = append(s.comments, strings.TrimSpace(line))
+	return nil
+}
+
+// readPixels reads the pixels of image after its header.
+func (s *netpbmScanner) readPixels(image Netpbm, maxval int) error {
+	scale := func(value int) (byte, error) {
+		if value > maxval {
+			return 0, fmt.Errorf("pixel value %v above the maxval %v", value, maxval)
+		}
+		return byte((value*255 + maxval/2) / maxval), nil
+	}
+	ended := func(err error) error {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return errors.New("image data ends early")
+		}
+		return err
+	}
+
+	switch image.Magic {
+	case "P5":
+		size := 1
+		if maxval > 255 {
+			size = 2
+		}
+		row := make([]byte, image.Width*size)
+		for y := range image.Pixels {
+			if _, err := io.ReadFull(s.reader, row); err != nil {
+				return ended(err)
+			}
+			for x := range image.Pixels[y] {
+				value := int(row[x])
+				if size == 2 {
+					value = int(row[2*x])<<8 | int(row[2*x+1])
+				}
+				var err error
+				if image.Pixels[y][x], err = scale(value); err != nil {
+					return err
+				}
+			}
+		}
+	case "P4":
+		row := make([]byte, (image.Width+7)/8)
+		for y := range image.Pixels {
+			if _, err := io.ReadFull(s.reader, row); err != nil {
+				return ended(err)
+			}
+			for x := range image.Pixels[y] {
+				if row[x/8]&(0x80>>uint(x%8)) != 0 {
+					image.Pixels[y][x] = 255
+				}
+			}
+		}
+	case "P2":
+		for y := range image.Pixels {
+			for x := range image.Pixels[y] {
+				token, err := s.token(false)
+				if err != nil {
+					return ended(err)
+				}
+				value, err := strconv.Atoi(token)
+				if err != nil || value < 0 {
+					return fmt.Errorf("incorrect pixel value %q", token)
+				}
+				if image.Pixels[y][x], err = scale(value); err != nil {
+					return err
+				}
+			}
+		}
+	case "P1":
+		//the pixels of plain bitmaps need not be separated by whitespace
+		for y := range image.Pixels {
+			for x := range image.Pixels[y] {
+				c, err := s.reader.ReadByte()
+				for err == nil && (isNetpbmSpace(c) || c == '#') {
+					if c == '#' {
+						err = s.comment()
+					}
+					if err == nil {
+						c, err = s.reader.ReadByte()
+					}
+				}
+				switch {
+				case err != nil:
+					return ended(err)
+				case c == '1':
+					image.Pixels[y][x] = 255
+				case c != '0':
+					return fmt.Errorf("incorrect bit %q", c)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func isNetpbmSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+// Write writes the image to writer in the format of its magic number, a row at a time.
+// Greymaps are written with a maxval of 255, and pixels of bitmaps are set if they are at least 128.
+func (image Netpbm) Write(writer io.Writer) error {
+	if !strings.Contains(" P1 P2 P4 P5 ", " "+image.Magic+" ") {
+		return fmt.Errorf("cannot write the netpbm format %q, expected P1, P2, P4 or P5", image.Magic)
+	}
+	buffered := bufio.NewWriter(writer)
+	buffered.WriteString(image.Magic + "\n")
+	for _, comment := range image.Comments {
+		buffered.WriteString("# " + comment + "\n")
+	}
+	fmt.Fprintf(buffered, "%d %d\n", image.Width, image.Height)
+
+	switch image.Magic {
+	case "P5":
+		buffered.WriteString("255\n")
+		for _, row := range image.Pixels {
+			buffered.Write(row)
+		}
+	case "P4":
+		packed := make([]byte, (image.Width+7)/8)
+		for _, row := range image.Pixels {
+			for i := range packed {
+				packed[i] = 0
+			}
+			for x, pixel := range row {
+				if pixel >= 128 {
+					packed[x/8] |= 0x80 >> uint(x%8)
+				}
+			}
+			buffered.Write(packed)
+		}
+	case "P2", "P1":
+		if image.Magic == "P2" {
+			buffered.WriteString("255\n")
+		}
+		for _, row := range image.Pixels {
+			line := 0
+			for _, pixel := range row {
+				value := strconv.Itoa(int(pixel))
+				if image.Magic == "P1" {
+					value = "0"
+					if pixel >= 128 {
+						value = "1"
+					}
+				}
+				if line > 0 && line+1+len(value) > netpbmLineLength {
+					buffered.WriteByte('\n')
+					line = 0
+				} else if line > 0 {
+					buffered.WriteByte(' ')
+					line++
+				}
+				buffered.WriteString(value)
+				line += len(value)
+			}
+			buffered.WriteByte('\n')
+		}
+	}
+	return buffered.Flush()
+}
+
+// AliveCells returns the cells of the image which are alive, rounding grey pixels to the nearer of dead and alive
+// as the engine does under two state rules.
+func (image Netpbm) AliveCells() []Cell {
+	var cells []Cell
+	for y, row := range image.Pixels {
+		for x, pixel := range row {
+			if pixel >= 128 {
+				cells = append(cells, Cell{X: x, Y: y})
+			}
+		}
+	}
+	return cells
+}
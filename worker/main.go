@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+
+	"uk.ac.bris.cs/gameoflife/gol"
+	"uk.ac.bris.cs/gameoflife/util"
+)
+
+// main starts a worker which registers with the broker and processes the strips of the world it is sent.
+func main() {
+	port := flag.String(
+		"port",
+		"8040",
+		"Specify the port to listen on for the broker. Defaults to 8040.")
+
+	broker := flag.String(
+		"broker",
+		"127.0.0.1:8030",
+		"Specify the address of the broker to register with. Defaults to 127.0.0.1:8030.")
+
+	flag.Parse()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:"+*port)
+	util.Check(err)
+	fmt.Println("Worker listening on", listener.Addr())
+
+	util.Check(gol.ServeWorker(listener, *broker))
+}